@@ -0,0 +1,113 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ServeAttachment writes the decompressed content of the named file to w as
+// a download, i.e. with a "Content-Disposition: attachment" header suggesting
+// filename to save it as. Non-ASCII file names are encoded as per RFC 5987,
+// with an ASCII approximation for older clients. Conditional and range
+// requests are supported, except for files opened in streaming mode. Methods
+// are handled like by CompressionMiddleware, i.e. only GET and HEAD requests
+// are answered with the file.
+func (compressed FileSystem) ServeAttachment(w http.ResponseWriter, r *http.Request, path, filename string) {
+	if !checkMethod(w, r) {
+		return
+	}
+	f, name, err := compressed.openResolved(r.Context(), path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	if stat, err := f.Stat(); err != nil || stat.IsDir() {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", contentDisposition(filename))
+	if sf, ok := f.(*File); ok && sf.reader != nil {
+		compressed.serveStream(w, r, name, sf)
+		return
+	}
+	content, err := io.ReadAll(f)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	ctype := typeByExtension(name)
+	if ctype == "" {
+		ctype = http.DetectContentType(content)
+	}
+	w.Header().Set("Content-Type", ctype)
+	w.Header().Set("ETag", compressed.etag(name, content))
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(content))
+}
+
+// contentDisposition returns the value of a Content-Disposition header for an
+// attachment to be saved as filename.
+func contentDisposition(filename string) string {
+	var ascii strings.Builder
+	isASCII := true
+	for _, r := range filename {
+		switch {
+		case r == '"' || r == '\\':
+			ascii.WriteByte('\\')
+			ascii.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			ascii.WriteByte('_')
+		case r > 0x7f:
+			isASCII = false
+			ascii.WriteByte('_')
+		default:
+			ascii.WriteRune(r)
+		}
+	}
+	disposition := `attachment; filename="` + ascii.String() + `"`
+	if !isASCII {
+		disposition += "; filename*=UTF-8''" + encodeRFC5987(filename)
+	}
+	return disposition
+}
+
+// encodeRFC5987 percent-encodes all bytes of s that aren't an attr-char as
+// defined by RFC 5987.
+func encodeRFC5987(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("!#$&+-.^_`|~", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0xf])
+	}
+	return b.String()
+}
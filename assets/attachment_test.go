@@ -0,0 +1,119 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestServeAttachment(t *testing.T) {
+	rec := httptest.NewRecorder()
+	testFS.ServeAttachment(rec, httptest.NewRequest(http.MethodGet, "/export", nil), "testdata/compressed", "export.txt")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status is wrong, expected %d, got %d", http.StatusOK, rec.Code)
+	}
+	if body := rec.Body.String(); body != "foo\n" {
+		t.Fatalf("body is wrong, got %q", body)
+	}
+	if disposition := rec.Header().Get("Content-Disposition"); disposition != `attachment; filename="export.txt"` {
+		t.Fatalf("Content-Disposition is wrong, got %q", disposition)
+	}
+	if ctype := rec.Header().Get("Content-Type"); ctype != "text/plain; charset=utf-8" {
+		t.Fatalf("Content-Type is wrong, got %q", ctype)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag")
+	}
+
+	// Conditional and range requests are supported.
+	req := httptest.NewRequest(http.MethodGet, "/export", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	testFS.ServeAttachment(rec, req, "testdata/compressed", "export.txt")
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("status is wrong, expected %d, got %d", http.StatusNotModified, rec.Code)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/export", nil)
+	req.Header.Set("Range", "bytes=1-2")
+	rec = httptest.NewRecorder()
+	testFS.ServeAttachment(rec, req, "testdata/compressed", "export.txt")
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "oo" {
+		t.Fatalf("expected partial content %q, got %d %q", "oo", rec.Code, rec.Body.String())
+	}
+
+	for _, path := range []string{"testdata/missing", "testdata", "/testdata/compressed"} {
+		rec = httptest.NewRecorder()
+		testFS.ServeAttachment(rec, httptest.NewRequest(http.MethodGet, "/export", nil), path, "export.txt")
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: status is wrong, expected %d, got %d", path, http.StatusNotFound, rec.Code)
+		}
+	}
+
+	// Only GET and HEAD requests are answered with the file.
+	for method, expected := range map[string]int{
+		http.MethodHead:    http.StatusOK,
+		http.MethodOptions: http.StatusNoContent,
+		http.MethodPost:    http.StatusMethodNotAllowed,
+		http.MethodPut:     http.StatusMethodNotAllowed,
+	} {
+		rec = httptest.NewRecorder()
+		testFS.ServeAttachment(rec, httptest.NewRequest(method, "/export", nil), "testdata/compressed", "export.txt")
+		if rec.Code != expected {
+			t.Fatalf("%s: status is wrong, expected %d, got %d", method, expected, rec.Code)
+		}
+		if method != http.MethodHead && rec.Header().Get("Content-Disposition") != "" {
+			t.Fatalf("%s: expected no Content-Disposition", method)
+		}
+	}
+}
+
+func TestServeAttachmentResolvesOnce(t *testing.T) {
+	var calls []string
+	fsys, err := NewWithOptions(EmbedFS,
+		WithAliases(map[string]string{"latest": "testdata/compressed"}),
+		WithFaultInjector(func(path string) error {
+			calls = append(calls, path)
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	fsys.ServeAttachment(rec, httptest.NewRequest(http.MethodGet, "/export", nil), "latest", "export.txt")
+	if rec.Code != http.StatusOK || rec.Body.String() != "foo\n" {
+		t.Fatalf("expected the aliased file, got %d %q", rec.Code, rec.Body.String())
+	}
+	if expected := []string{"testdata/compressed"}; !reflect.DeepEqual(calls, expected) {
+		t.Fatalf("expected the file to be resolved once, got %v", calls)
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	for filename, expected := range map[string]string{
+		"report.csv":      `attachment; filename="report.csv"`,
+		`say "hi".txt`:    `attachment; filename="say \"hi\".txt"`,
+		"résumé 2024.pdf": `attachment; filename="r_sum_ 2024.pdf"; filename*=UTF-8''r%C3%A9sum%C3%A9%202024.pdf`,
+		"数据.json":         `attachment; filename="__.json"; filename*=UTF-8''%E6%95%B0%E6%8D%AE.json`,
+		"line\nbreak.txt": `attachment; filename="line_break.txt"`,
+	} {
+		if actual := contentDisposition(filename); actual != expected {
+			t.Fatalf("disposition of %q is wrong, expected %q, got %q", filename, expected, actual)
+		}
+	}
+}
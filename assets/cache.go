@@ -0,0 +1,130 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"container/list"
+	"embed"
+	"sync"
+)
+
+// CacheOptions configures the cache of decompressed content set up by
+// NewCached and WithCache.
+type CacheOptions struct {
+	// MaxBytes is the maximum total size of the cached content. The least
+	// recently used files are evicted to stay below it, and files larger
+	// than it aren't cached at all.
+	MaxBytes int64
+
+	// OnHit, OnMiss and OnEvict, if not nil, are called with the name of the
+	// FileSystem set with WithName, if any, and the name of the file when it
+	// is found in the cache, when it has to be decompressed, and when it is
+	// evicted, respectively. They allow exporting metrics.
+	OnHit   func(fsName, name string)
+	OnMiss  func(fsName, name string)
+	OnEvict func(fsName, name string)
+}
+
+// NewCached returns a FileSystem for the given embed FS like New, which keeps
+// the decompressed content of recently opened files in memory, so that hot
+// files aren't decompressed over and over again.
+func NewCached(fs embed.FS, opts CacheOptions) FileSystem {
+	compressed := New(fs)
+	compressed.opts.cache = newDecodeCache(opts)
+	return compressed
+}
+
+// decodeCache is an LRU cache of decompressed content, keyed by the name a
+// file is opened with.
+type decodeCache struct {
+	opts CacheOptions
+
+	mtx  sync.Mutex
+	size int64
+	// The cached entries, most recently used first.
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	name    string
+	content []byte
+}
+
+func newDecodeCache(opts CacheOptions) *decodeCache {
+	return &decodeCache{opts: opts, lru: list.New(), entries: map[string]*list.Element{}}
+}
+
+// get returns the cached content of the named file, if any. fsName is passed
+// to the callbacks.
+func (c *decodeCache) get(fsName, name string) ([]byte, bool) {
+	c.mtx.Lock()
+	e, ok := c.entries[name]
+	if ok {
+		c.lru.MoveToFront(e)
+	}
+	c.mtx.Unlock()
+
+	if !ok {
+		if c.opts.OnMiss != nil {
+			c.opts.OnMiss(fsName, name)
+		}
+		return nil, false
+	}
+	if c.opts.OnHit != nil {
+		c.opts.OnHit(fsName, name)
+	}
+	return e.Value.(*cacheEntry).content, true
+}
+
+// add caches the content of the named file, evicting the least recently used
+// files as needed. fsName is passed to the callbacks.
+func (c *decodeCache) add(fsName, name string, content []byte) {
+	size := int64(len(content))
+	if size > c.opts.MaxBytes {
+		return
+	}
+
+	var evicted []string
+	c.mtx.Lock()
+	if _, ok := c.entries[name]; ok {
+		// Added concurrently.
+		c.mtx.Unlock()
+		return
+	}
+	for c.size+size > c.opts.MaxBytes {
+		e := c.lru.Back()
+		entry := c.lru.Remove(e).(*cacheEntry)
+		delete(c.entries, entry.name)
+		c.size -= int64(len(entry.content))
+		evicted = append(evicted, entry.name)
+	}
+	c.entries[name] = c.lru.PushFront(&cacheEntry{name: name, content: content})
+	c.size += size
+	c.mtx.Unlock()
+
+	if c.opts.OnEvict != nil {
+		for _, name := range evicted {
+			c.opts.OnEvict(fsName, name)
+		}
+	}
+}
+
+// cache returns the cache set with NewCached or WithCache, if any.
+func (compressed FileSystem) cache() *decodeCache {
+	if compressed.opts == nil {
+		return nil
+	}
+	return compressed.opts.cache
+}
@@ -0,0 +1,133 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"context"
+	"io/fs"
+	"reflect"
+	"testing"
+)
+
+func TestNewCached(t *testing.T) {
+	var events []string
+	fsys := NewCached(siteFS, CacheOptions{
+		MaxBytes: 50,
+		OnHit:    func(_, name string) { events = append(events, "hit "+name) },
+		OnMiss:   func(_, name string) { events = append(events, "miss "+name) },
+		OnEvict:  func(_, name string) { events = append(events, "evict "+name) },
+	})
+
+	for _, name := range []string{
+		"testdata/site/style.css",
+		"testdata/site/guide/setup/index.html",
+		"testdata/site/style.css",
+		// Uncompressed, thus not cached.
+		"testdata/site/app.js",
+		// Evicts the least recently used file to fit.
+		"testdata/site/static/app.3f9a1c.js",
+		"testdata/site/style.css",
+		"testdata/site/guide/setup/index.html",
+	} {
+		expected, err := fs.ReadFile(New(siteFS), name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != string(expected) {
+			t.Fatalf("content of %s is wrong, expected %q, got %q", name, expected, content)
+		}
+	}
+
+	expected := []string{
+		"miss testdata/site/style.css",
+		"miss testdata/site/guide/setup/index.html",
+		"hit testdata/site/style.css",
+		"miss testdata/site/static/app.3f9a1c.js",
+		"evict testdata/site/guide/setup/index.html",
+		"hit testdata/site/style.css",
+		"miss testdata/site/guide/setup/index.html",
+		"evict testdata/site/static/app.3f9a1c.js",
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("events are wrong, expected\n%q\ngot\n%q", expected, events)
+	}
+}
+
+func TestNewCachedTooLarge(t *testing.T) {
+	misses := 0
+	fsys := NewCached(siteFS, CacheOptions{
+		MaxBytes: 10,
+		OnMiss:   func(string, string) { misses++ },
+	})
+	for i := 0; i < 2; i++ {
+		if _, err := fs.ReadFile(fsys, "testdata/site/style.css"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if misses != 2 {
+		t.Fatalf("expected files larger than the cache not to be cached, got %d misses", misses)
+	}
+}
+
+func TestWithCache(t *testing.T) {
+	fsys, err := NewWithOptions(siteFS, WithCache(CacheOptions{MaxBytes: 1024}), WithMaxConcurrentDecodes(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.ReadFile(fsys, "testdata/site/style.css"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Cached files don't need a slot to decompress.
+	release, err := fsys.acquireDecode(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	f, err := fsys.OpenContext(ctx, "testdata/site/style.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if _, err := NewWithOptions(siteFS, WithCache(CacheOptions{})); err == nil {
+		t.Fatal("expected an error for a cache without a size")
+	}
+}
+
+func TestCacheFSName(t *testing.T) {
+	var names []string
+	record := func(fsName, _ string) { names = append(names, fsName) }
+	// The cache is set up before the name.
+	fsys, err := NewWithOptions(siteFS, WithCache(CacheOptions{MaxBytes: 1024, OnHit: record, OnMiss: record}), WithName("ui"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var accessed []string
+	fsys = fsys.WithAccessLog(func(fsName, _ string, _ bool) { accessed = append(accessed, fsName) })
+	for i := 0; i < 2; i++ {
+		if _, err := fs.ReadFile(fsys, "testdata/site/style.css"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if expected := []string{"ui", "ui"}; !reflect.DeepEqual(names, expected) || !reflect.DeepEqual(accessed, expected) {
+		t.Fatalf("FileSystem names are wrong, got %q from the cache and %q from the access log", names, accessed)
+	}
+}
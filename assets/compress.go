@@ -0,0 +1,263 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultSkipExtensions lists the extensions of file formats which are already
+// compressed, and which Compress copies as they are unless configured
+// otherwise.
+var DefaultSkipExtensions = []string{
+	".png", ".jpg", ".jpeg", ".gif", ".webp", ".avif", ".ico",
+	".woff", ".woff2", ".mp3", ".mp4", ".ogg", ".webm", ".zip",
+	gzipSuffix, brSuffix, zstdSuffix,
+}
+
+// DefaultCompressManifestPath returns the path of the manifest Compress writes
+// for dstDir unless configured otherwise, which is next to dstDir. It is
+// absolute, so that it is also well-defined for dstDir ".", e.g.
+// "/src/ui/static-manifest.json" for "static" in "/src/ui".
+func DefaultCompressManifestPath(dstDir string) string {
+	dir, err := filepath.Abs(dstDir)
+	if err != nil {
+		dir = filepath.Clean(dstDir)
+	}
+	return dir + "-manifest.json"
+}
+
+// CompressOptions configures Compress.
+type CompressOptions struct {
+	// MinSize is the size in bytes below which files are copied uncompressed.
+	MinSize int64
+	// Level is the gzip compression level, gzip.BestCompression if zero.
+	Level int
+	// SkipExtensions lists the extensions of files copied uncompressed,
+	// DefaultSkipExtensions if nil.
+	SkipExtensions []string
+	// Encoders holds compressors for the additional content codings "br" and
+	// "zstd", such as those passed to RegisterEncoder. Files are written with
+	// each of them next to the gzip variant.
+	Encoders map[string]EncoderFunc
+	// ManifestPath is the path the manifest is written to,
+	// DefaultCompressManifestPath if empty. It must be outside of the
+	// destination directory, so that the manifest isn't embedded and served
+	// along with the files.
+	ManifestPath string
+}
+
+// CompressedFile describes a file written by Compress.
+type CompressedFile struct {
+	// Size is the size of the original content.
+	Size int64 `json:"size"`
+	// Hash is the hex encoded SHA-256 hash of the original content, as
+	// returned by FileSystem.Manifest.
+	Hash string `json:"hash"`
+	// Encodings are the content codings the file was stored with, empty if it
+	// was copied uncompressed.
+	Encodings []string `json:"encodings,omitempty"`
+}
+
+// CompressManifest holds the files written by Compress, keyed by the slash
+// separated names they can be opened with.
+type CompressManifest map[string]CompressedFile
+
+// ReadCompressManifest reads a manifest written by Compress from fsys.
+func ReadCompressManifest(fsys fs.FS, name string) (CompressManifest, error) {
+	b, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	var m CompressManifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("assets: parsing manifest %s: %w", name, err)
+	}
+	return m, nil
+}
+
+// Size returns the original size of the named file. It can be passed to
+// WithSizeFunc for the files to be sized without decoding them. The name is
+// relative to the destination directory, so a prefix under which it is
+// embedded must be removed first.
+func (m CompressManifest) Size(name string) (int64, bool) {
+	f, ok := m[name]
+	return f.Size, ok
+}
+
+// Hashes returns the hashes of all files, in the format of FileSystem.Manifest.
+func (m CompressManifest) Hashes() map[string]string {
+	hashes := make(map[string]string, len(m))
+	for name, f := range m {
+		hashes[name] = f.Hash
+	}
+	return hashes
+}
+
+// Compress walks srcDir and writes all files to dstDir, gzip compressed with a
+// ".gz" suffix, as well as with each of the encoders set in opts. Files which
+// are smaller than opts.MinSize, have one of the skipped extensions or don't
+// get any smaller are copied as they are. The output is deterministic, so that
+// it can be committed, and a manifest describing all files is written as JSON
+// next to dstDir. Compress is meant to be run with go:generate from a small
+// main package, before embedding dstDir.
+func Compress(srcDir, dstDir string, opts CompressOptions) (CompressManifest, error) {
+	if opts.Level == 0 {
+		opts.Level = gzip.BestCompression
+	}
+	if opts.SkipExtensions == nil {
+		opts.SkipExtensions = DefaultSkipExtensions
+	}
+	if opts.ManifestPath == "" {
+		opts.ManifestPath = DefaultCompressManifestPath(dstDir)
+	}
+	if within, err := isWithin(opts.ManifestPath, dstDir); err != nil {
+		return nil, err
+	} else if within {
+		return nil, fmt.Errorf("assets: manifest %s must not be within %s", opts.ManifestPath, dstDir)
+	}
+	for encoding := range opts.Encoders {
+		if encoding == "gzip" || suffixFor(encoding) == "" {
+			return nil, fmt.Errorf("assets: unsupported encoding %q", encoding)
+		}
+	}
+
+	manifest := CompressManifest{}
+	err := fs.WalkDir(os.DirFS(srcDir), ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(dstDir, filepath.FromSlash(name))
+		if d.IsDir() {
+			return os.MkdirAll(dst, 0o755)
+		}
+		content, err := os.ReadFile(filepath.Join(srcDir, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(content)
+		file := CompressedFile{Size: int64(len(content)), Hash: hex.EncodeToString(sum[:])}
+		if file.Encodings, err = compressFile(dst, content, opts); err != nil {
+			return fmt.Errorf("assets: compressing %s: %w", name, err)
+		}
+		manifest[name] = file
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	b = append(b, '\n')
+	if err := os.WriteFile(opts.ManifestPath, b, 0o644); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// isWithin reports whether the file path is within dir.
+func isWithin(path, dir string) (bool, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false, err
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false, err
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil {
+		return false, nil
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)), nil
+}
+
+// compressFile writes content to dst with all configured encodings, returning
+// those which were written in order of preference. If none were, content is
+// written to dst as it is.
+func compressFile(dst string, content []byte, opts CompressOptions) ([]string, error) {
+	if int64(len(content)) < opts.MinSize || skipsCompression(dst, opts.SkipExtensions) {
+		return nil, os.WriteFile(dst, content, 0o644)
+	}
+
+	// Not using Recompress, which would decompress gzip content instead of
+	// compressing it again.
+	var gz bytes.Buffer
+	gw, err := gzip.NewWriterLevel(&gz, opts.Level)
+	if err != nil {
+		return nil, err
+	}
+	gw.OS = gzipOSUnknown
+	if _, err := gw.Write(content); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	if gz.Len() >= len(content) {
+		return nil, os.WriteFile(dst, content, 0o644)
+	}
+
+	var encodings []string
+	for _, se := range storedEncodings {
+		var b []byte
+		if se.encoding == "gzip" {
+			b = gz.Bytes()
+		} else {
+			enc, ok := opts.Encoders[se.encoding]
+			if !ok {
+				continue
+			}
+			var buf bytes.Buffer
+			w := enc(&buf)
+			if _, err := w.Write(content); err != nil {
+				return nil, err
+			}
+			if err := w.Close(); err != nil {
+				return nil, err
+			}
+			b = buf.Bytes()
+		}
+		if err := os.WriteFile(dst+se.suffix, b, 0o644); err != nil {
+			return nil, err
+		}
+		encodings = append(encodings, se.encoding)
+	}
+	return encodings, nil
+}
+
+// skipsCompression reports whether name has one of the extensions, ignoring
+// case.
+func skipsCompression(name string, exts []string) bool {
+	ext := strings.ToLower(path.Ext(filepath.ToSlash(name)))
+	for _, e := range exts {
+		if strings.ToLower(e) == ext {
+			return true
+		}
+	}
+	return false
+}
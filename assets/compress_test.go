@@ -0,0 +1,186 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompress(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	big := strings.Repeat("compressible ", 100)
+	for name, content := range map[string]string{
+		"big.txt":        big,
+		"small.txt":      "hi\n",
+		"logo.png":       big,
+		"js/app.js":      big,
+		"random.bin":     "\x00\xff\x13\x37\x42\x99\x01\x02\x03\x04\x05\x06\x07\x08\x09\x0a",
+		"js/app.STY.GIF": big,
+	} {
+		path := filepath.Join(src, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The fake brotli encoder writes gzip, like the test decoder reads it.
+	encoders := map[string]EncoderFunc{
+		"br": func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+	}
+	m, err := Compress(src, dst, CompressOptions{MinSize: 10, Encoders: encoders})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored := map[string][]string{
+		"big.txt":        {"br", "gzip"},
+		"small.txt":      nil,
+		"logo.png":       nil,
+		"js/app.js":      {"br", "gzip"},
+		"random.bin":     nil,
+		"js/app.STY.GIF": nil,
+	}
+	if len(m) != len(stored) {
+		t.Fatalf("expected %d files in the manifest, got %v", len(stored), m)
+	}
+	for name, expected := range stored {
+		if !reflect.DeepEqual(m[name].Encodings, expected) {
+			t.Errorf("expected %s to be stored with %v, got %v", name, expected, m[name].Encodings)
+		}
+		path := filepath.Join(dst, filepath.FromSlash(name))
+		if expected == nil {
+			if _, err := os.Stat(path); err != nil {
+				t.Errorf("expected %s to be copied: %v", name, err)
+			}
+			continue
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected no uncompressed copy of %s, got %v", name, err)
+		}
+		b, err := os.ReadFile(path + gzipSuffix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gr, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(gr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != big {
+			t.Errorf("unexpected content of %s", name)
+		}
+		if _, err := os.Stat(path + brSuffix); err != nil {
+			t.Errorf("expected a brotli variant of %s: %v", name, err)
+		}
+	}
+	if size, ok := m.Size("big.txt"); !ok || size != int64(len(big)) {
+		t.Errorf("expected size %d, got %d", len(big), size)
+	}
+
+	manifestPath := DefaultCompressManifestPath(dst)
+	read, err := ReadCompressManifest(os.DirFS(filepath.Dir(manifestPath)), filepath.Base(manifestPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, m) {
+		t.Errorf("expected the written manifest to match, got %v", read)
+	}
+
+	// The output is deterministic.
+	again := t.TempDir()
+	if _, err := Compress(src, again, CompressOptions{MinSize: 10, Encoders: encoders}); err != nil {
+		t.Fatal(err)
+	}
+	for _, paths := range [][2]string{
+		{filepath.Join(dst, "big.txt.gz"), filepath.Join(again, "big.txt.gz")},
+		{manifestPath, DefaultCompressManifestPath(again)},
+	} {
+		a, _ := os.ReadFile(paths[0])
+		b, _ := os.ReadFile(paths[1])
+		if !bytes.Equal(a, b) {
+			t.Errorf("expected %s to be identical across runs", paths[0])
+		}
+	}
+}
+
+func TestDefaultCompressManifestPath(t *testing.T) {
+	dir := t.TempDir()
+	for dstDir, expected := range map[string]string{
+		filepath.Join(dir, "static"):       filepath.Join(dir, "static-manifest.json"),
+		filepath.Join(dir, "static") + "/": filepath.Join(dir, "static-manifest.json"),
+	} {
+		if actual := DefaultCompressManifestPath(dstDir); actual != expected {
+			t.Errorf("%s: expected %s, got %s", dstDir, expected, actual)
+		}
+	}
+	// Relative directories, including ".", are made absolute.
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expected := DefaultCompressManifestPath("."), wd+"-manifest.json"; actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
+
+func TestCompressManifestPath(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "manifest.json"), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	manifestPath := filepath.Join(t.TempDir(), "assets.json")
+	if _, err := Compress(src, dst, CompressOptions{ManifestPath: manifestPath}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(manifestPath); err != nil {
+		t.Fatalf("expected the manifest to be written: %v", err)
+	}
+	// The destination only holds the compressed files, so that the manifest
+	// isn't embedded.
+	entries, err := os.ReadDir(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "manifest.json" {
+		t.Fatalf("expected only the source files in the destination, got %v", entries)
+	}
+
+	for _, inside := range []string{filepath.Join(dst, "manifest.json"), filepath.Join(dst, "sub", "manifest.json")} {
+		if _, err := Compress(src, dst, CompressOptions{ManifestPath: inside}); err == nil {
+			t.Fatalf("expected an error for the manifest path %s within the destination", inside)
+		}
+	}
+}
+
+func TestCompressUnsupportedEncoding(t *testing.T) {
+	encoders := map[string]EncoderFunc{
+		"deflate": func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+	}
+	if _, err := Compress(t.TempDir(), t.TempDir(), CompressOptions{Encoders: encoders}); err == nil {
+		t.Fatal("expected an error for an unsupported encoding")
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"strings"
+)
+
+// Concat returns the decompressed content of the named files concatenated in
+// order, separated by a newline or as set with WithConcatSeparator, e.g. to
+// serve several scripts as one bundle. Results are memoized keyed by the names
+// and content hashes of the files, so the returned slice must not be modified.
+func (compressed FileSystem) Concat(paths ...string) ([]byte, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("assets: no files to concatenate")
+	}
+	// Content is only read if its hash isn't known yet.
+	names := make([]string, len(paths))
+	contents := make([][]byte, len(paths))
+	var key strings.Builder
+	for i, path := range paths {
+		name, err := compressed.resolvePath("read", path)
+		if err != nil {
+			return nil, err
+		}
+		names[i] = name
+		etag, ok := compressed.knownETag(name)
+		if !ok {
+			if contents[i], err = fs.ReadFile(compressed, name); err != nil {
+				return nil, err
+			}
+			etag = compressed.etag(name, contents[i])
+		}
+		key.WriteString(name)
+		key.WriteString(etag)
+		key.WriteByte(0)
+	}
+	if compressed.bundles != nil {
+		if bundle, ok := compressed.bundles.Load(key.String()); ok {
+			return bundle.([]byte), nil
+		}
+	}
+
+	for i, name := range names {
+		if contents[i] != nil {
+			continue
+		}
+		content, err := fs.ReadFile(compressed, name)
+		if err != nil {
+			return nil, err
+		}
+		contents[i] = content
+	}
+	bundle := bytes.Join(contents, []byte(compressed.concatSeparator()))
+	if compressed.bundles != nil {
+		compressed.bundles.Store(key.String(), bundle)
+	}
+	return bundle, nil
+}
+
+// knownETag returns the memoized ETag of the content stored under key.
+func (compressed FileSystem) knownETag(key string) (string, bool) {
+	if compressed.etags == nil {
+		return "", false
+	}
+	etag, ok := compressed.etags.Load(key)
+	if !ok {
+		return "", false
+	}
+	return etag.(string), true
+}
+
+// concatSeparator returns the separator set with WithConcatSeparator.
+func (compressed FileSystem) concatSeparator() string {
+	if compressed.opts != nil && compressed.opts.concatSep != nil {
+		return *compressed.opts.concatSep
+	}
+	return "\n"
+}
@@ -0,0 +1,71 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import "testing"
+
+func TestConcat(t *testing.T) {
+	fsys := New(EmbedFS)
+	bundle, err := fsys.Concat("testdata/compressed", "testdata/uncompressed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "foo\n\nfoo\n"; string(bundle) != expected {
+		t.Fatalf("bundle is wrong, expected %q, got %q", expected, bundle)
+	}
+
+	// The second call is served from the cache.
+	cached, err := fsys.Concat("testdata/compressed", "testdata/uncompressed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &cached[0] != &bundle[0] {
+		t.Fatal("expected the bundle to be memoized")
+	}
+
+	// The order of the files matters.
+	reversed, err := fsys.Concat("testdata/uncompressed", "testdata/variants/data.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "foo\n\nvariant content\n"; string(reversed) != expected {
+		t.Fatalf("bundle is wrong, expected %q, got %q", expected, reversed)
+	}
+}
+
+func TestConcatSeparator(t *testing.T) {
+	fsys, err := NewWithOptions(EmbedFS, WithConcatSeparator(";\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := fsys.Concat("testdata/compressed", "testdata/uncompressed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "foo\n;\nfoo\n"; string(bundle) != expected {
+		t.Fatalf("bundle is wrong, expected %q, got %q", expected, bundle)
+	}
+}
+
+func TestConcatErrors(t *testing.T) {
+	if _, err := testFS.Concat(); err == nil {
+		t.Fatal("expected an error for no files")
+	}
+	if _, err := testFS.Concat("testdata/compressed", "testdata/missing"); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+	if _, err := testFS.Concat("/testdata/compressed"); err == nil {
+		t.Fatal("expected an error for an invalid path")
+	}
+}
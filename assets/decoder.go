@@ -0,0 +1,166 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DecoderFunc returns a reader decompressing the content read from r.
+type DecoderFunc func(r io.Reader) (io.ReadCloser, error)
+
+// errNoDecoder is returned when opening a file only stored with codings no
+// decoder is registered for.
+var errNoDecoder = errors.New("no decoder registered")
+
+var (
+	decodersMtx sync.RWMutex
+	// decoders holds the registered decompressors, keyed by content coding.
+	decoders = map[string]DecoderFunc{
+		"gzip": func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	}
+
+	// decoderProbes holds a tiny stream of the content "ok" for each known
+	// content coding, which registered decoders must be able to decode.
+	decoderProbes = map[string]string{
+		"gzip": "\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\x03\xcb\xcf\x06\x00\x47\xdd\xdc\x79\x02\x00\x00\x00",
+		"zstd": "\x28\xb5\x2f\xfd\x04\x00\x11\x00\x00\x6f\x6b\xf9\x5c\x14\x16",
+		"br":   "\x8b\x00\x80\x6f\x6b\x03",
+	}
+)
+
+// RegisterDecoder registers fn as the decompressor for the given content
+// coding (e.g. "zstd"). Registering an already known coding replaces its
+// decompressor. Open uses it for files stored with the coding, i.e. with a
+// ".zst" or ".br" suffix, while gzip compressed files are always decompressed
+// with compress/gzip. For the codings gzip, zstd and br, NewWithOptions checks
+// that the registered decompressors actually work, so that e.g. a stub left in
+// place by a build excluding the codec library is caught at startup rather
+// than when first opening a file.
+func RegisterDecoder(encoding string, fn DecoderFunc) {
+	decodersMtx.Lock()
+	defer decodersMtx.Unlock()
+	decoders[strings.ToLower(encoding)] = fn
+}
+
+// lookupDecoder returns the decompressor registered for encoding, if any.
+func lookupDecoder(encoding string) (DecoderFunc, bool) {
+	decodersMtx.RLock()
+	defer decodersMtx.RUnlock()
+	fn, ok := decoders[encoding]
+	return fn, ok
+}
+
+// newDecoder returns a reader decompressing r, which is stored with the
+// encoding.
+func newDecoder(encoding string, r io.Reader) (io.ReadCloser, error) {
+	if encoding == "gzip" {
+		gr, err := getGzipReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return pooledGzipReader{gr}, nil
+	}
+	fn, ok := lookupDecoder(encoding)
+	if !ok {
+		return nil, fmt.Errorf("%w for %s", errNoDecoder, encoding)
+	}
+	return fn(r)
+}
+
+// trimDecodable returns name without the suffix of the coding it is stored
+// with, if Open can decompress files stored with that coding.
+func (compressed FileSystem) trimDecodable(name string) (string, bool) {
+	for _, se := range compressed.codings() {
+		if !strings.HasSuffix(name, se.suffix) {
+			continue
+		}
+		if _, ok := lookupDecoder(se.encoding); !ok {
+			return name, false
+		}
+		return strings.TrimSuffix(name, se.suffix), true
+	}
+	return name, false
+}
+
+// decodeEncoding returns the coding Open decompresses the named file from, the
+// first of those it is stored with, in the order set with WithDecodePreference,
+// that there is a decoder for. If the file isn't stored compressed at all,
+// gzip is returned, so that opening it reports the file as missing.
+func (compressed FileSystem) decodeEncoding(name string) (string, error) {
+	var undecodable string
+	for _, encoding := range compressed.decodePreference() {
+		if !compressed.hasEncoding(name, encoding) {
+			continue
+		}
+		if _, ok := lookupDecoder(encoding); ok {
+			return encoding, nil
+		}
+		if undecodable == "" {
+			undecodable = encoding
+		}
+	}
+	if undecodable != "" {
+		return "", compressed.decodeError(name+suffixFor(undecodable), fmt.Errorf("%w for %s", errNoDecoder, undecodable))
+	}
+	return "gzip", nil
+}
+
+// checkDecoders returns an error naming the first registered decoder, in
+// alphabetical order, that fails to decode its probe.
+func checkDecoders() error {
+	decodersMtx.RLock()
+	encodings := make([]string, 0, len(decoders))
+	for encoding := range decoders {
+		encodings = append(encodings, encoding)
+	}
+	decodersMtx.RUnlock()
+	sort.Strings(encodings)
+
+	for _, encoding := range encodings {
+		probe, ok := decoderProbes[encoding]
+		if !ok {
+			continue
+		}
+		fn, _ := lookupDecoder(encoding)
+		if err := checkDecoder(fn, probe); err != nil {
+			return fmt.Errorf("assets: %s decoder doesn't work: %w", encoding, err)
+		}
+	}
+	return nil
+}
+
+// checkDecoder decodes probe with fn, expecting "ok".
+func checkDecoder(fn DecoderFunc, probe string) error {
+	rc, err := fn(strings.NewReader(probe))
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(content, []byte("ok")) {
+		return fmt.Errorf("decoded %q, expected %q", content, "ok")
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"compress/gzip"
+	"embed"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// registerTestDecoder registers fn for encoding until the test is done.
+func registerTestDecoder(t *testing.T, encoding string, fn DecoderFunc) {
+	encoding = strings.ToLower(encoding)
+	prev, ok := lookupDecoder(encoding)
+	RegisterDecoder(encoding, fn)
+	t.Cleanup(func() {
+		decodersMtx.Lock()
+		defer decodersMtx.Unlock()
+		if ok {
+			decoders[encoding] = prev
+		} else {
+			delete(decoders, encoding)
+		}
+	})
+}
+
+func TestCheckDecoders(t *testing.T) {
+	if err := checkDecoders(); err != nil {
+		t.Fatalf("unexpected error for the builtin decoders: %v", err)
+	}
+
+	// Decoders of unknown codings can't be checked.
+	registerTestDecoder(t, "x-test", func(r io.Reader) (io.ReadCloser, error) {
+		return nil, errors.New("not implemented")
+	})
+	if _, err := NewWithOptions(EmbedFS); err != nil {
+		t.Fatalf("unexpected error for an unknown coding: %v", err)
+	}
+
+	registerTestDecoder(t, "ZSTD", func(r io.Reader) (io.ReadCloser, error) {
+		return nil, errors.New("zstd support not compiled in")
+	})
+	_, err := NewWithOptions(EmbedFS)
+	if err == nil || !strings.Contains(err.Error(), "zstd") {
+		t.Fatalf("expected an error naming the zstd decoder, got %v", err)
+	}
+
+	// A decoder returning garbage is caught as well.
+	registerTestDecoder(t, "zstd", func(r io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(r), nil
+	})
+	if _, err := NewWithOptions(EmbedFS); err == nil {
+		t.Fatal("expected an error for a decoder returning the wrong content")
+	}
+}
+
+func TestDecoderProbes(t *testing.T) {
+	// The gzip probe is verified by the builtin decoder, the others
+	// require codec libraries this module doesn't depend on.
+	if err := checkDecoder(func(r io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("ok")), nil
+	}, decoderProbes["br"]); err != nil {
+		t.Fatal(err)
+	}
+	fn, _ := lookupDecoder("gzip")
+	if err := checkDecoder(fn, decoderProbes["gzip"]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+//go:embed testdata/decoders
+var decodersFS embed.FS
+
+// newDecodersFS returns a FileSystem for decodersFS recognizing all stored
+// codings. Unlike NewWithOptions, it doesn't check the fake decoders the tests
+// register.
+func newDecodersFS() FileSystem {
+	compressed := newFileSystem(decodersFS)
+	compressed.index = newIndex(decodersFS, false, compressed.codings())
+	return compressed
+}
+
+// The ".br" fixtures are gzip compressed, as there is no brotli decoder in
+// the standard library.
+func gzipAsBrotli(t *testing.T) {
+	registerTestDecoder(t, "br", func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	})
+}
+
+func TestOpenRegisteredDecoder(t *testing.T) {
+	fsys := newDecodersFS()
+	if _, err := fsys.Open("testdata/decoders/only.txt"); !errors.Is(err, errNoDecoder) {
+		t.Fatalf("expected an error for the missing decoder, got %v", err)
+	}
+	names, err := fs.Glob(fsys, "testdata/decoders/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Files stored with codings without a decoder are listed by their stored
+	// names.
+	expectedNames := []string{"testdata/decoders/both.txt", "testdata/decoders/both.txt.br", "testdata/decoders/only.txt.br"}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Fatalf("names are wrong, expected %q, got %q", expectedNames, names)
+	}
+
+	gzipAsBrotli(t)
+	for _, streaming := range []bool{false, true} {
+		fsys := newDecodersFS()
+		if streaming {
+			WithStreaming()(fsys.opts)
+		}
+		for path, expected := range map[string]string{
+			// Preferred over the gzip copy.
+			"testdata/decoders/both.txt": "br\n",
+			"testdata/decoders/only.txt": "br only\n",
+		} {
+			content, err := fs.ReadFile(fsys, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != expected {
+				t.Fatalf("content of %s is wrong, expected %q, got %q", path, expected, content)
+			}
+		}
+		stat, err := fs.Stat(fsys, "testdata/decoders/only.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stat.Name() != "only.txt" {
+			t.Fatalf("name is wrong, expected only.txt, got %s", stat.Name())
+		}
+	}
+
+	entries, err := fs.ReadDir(newDecodersFS(), "testdata/decoders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names = nil
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if expected := []string{"both.txt", "only.txt"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("entries are wrong, expected %q, got %q", expected, names)
+	}
+}
+
+func TestWithDecodePreference(t *testing.T) {
+	gzipAsBrotli(t)
+	// NewWithOptions would reject the fake brotli decoder.
+	fsys := newDecodersFS()
+	if err := WithDecodePreference("gzip")(fsys.opts); err != nil {
+		t.Fatal(err)
+	}
+	content, err := fs.ReadFile(fsys, "testdata/decoders/both.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "gzip\n" {
+		t.Fatalf("expected the gzip copy to be decoded, got %q", content)
+	}
+	// Codings not listed are still used.
+	if _, err := fs.ReadFile(fsys, "testdata/decoders/only.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewWithOptions(decodersFS, WithDecodePreference("lzma")); err == nil {
+		t.Fatal("expected an error for an unknown encoding")
+	}
+}
+
+func TestStreamUnknownSize(t *testing.T) {
+	gzipAsBrotli(t)
+	fsys := newDecodersFS()
+	WithStreaming()(fsys.opts)
+	handler := fsys.CompressionMiddleware(http.NotFoundHandler())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/testdata/decoders/only.txt", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "br only\n" {
+		t.Fatalf("unexpected response %d %q", rec.Code, rec.Body.String())
+	}
+	if _, ok := rec.Header()["Content-Length"]; ok {
+		t.Fatalf("expected no Content-Length for an unknown size, got %q", rec.Header().Get("Content-Length"))
+	}
+	if etag := rec.Header().Get("ETag"); !strings.HasPrefix(etag, `W/"`) || strings.Contains(etag, "-") {
+		t.Fatalf("expected a weak ETag without size, got %q", etag)
+	}
+}
+
+func TestNewGzipOnly(t *testing.T) {
+	gzipAsBrotli(t)
+	fsys := New(decodersFS)
+
+	// The brotli variant is ignored even though there is a decoder for it.
+	content, err := fs.ReadFile(fsys, "testdata/decoders/both.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "gzip\n" {
+		t.Fatalf("expected the gzip copy to be decoded, got %q", content)
+	}
+	if _, err := fsys.Open("testdata/decoders/only.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected fs.ErrNotExist, got %v", err)
+	}
+	if _, err := fs.ReadFile(fsys, "testdata/decoders/only.txt.br"); err != nil {
+		t.Fatalf("expected the brotli file to be served as it is, got %v", err)
+	}
+
+	entries, err := fs.ReadDir(fsys, "testdata/decoders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if expected := []string{"both.txt", "both.txt.br", "only.txt.br"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("expected entries %q, got %q", expected, names)
+	}
+	if !fsys.index.lazy {
+		t.Fatal("expected New to index lazily")
+	}
+}
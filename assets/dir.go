@@ -0,0 +1,160 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+)
+
+// wrapDir returns f wrapped as a *Dir if it is a directory, or f otherwise.
+func (compressed FileSystem) wrapDir(name string, f fs.File) fs.File {
+	if d, ok := f.(fs.ReadDirFile); ok {
+		return &Dir{ReadDirFile: d, fsys: compressed, name: name}
+	}
+	return f
+}
+
+// Dir is a directory of a FileSystem. Its entries are listed by the names
+// they can be opened with, i.e. compressed files without their suffix, unless
+// there is no decoder for their coding.
+type Dir struct {
+	fs.ReadDirFile
+	fsys FileSystem
+	name string
+
+	// The entries of the directory, read on the first call to ReadDir().
+	entries []fs.DirEntry
+	read    bool
+	// Offset for calls to ReadDir().
+	offset int
+}
+
+// ReadDir implements the fs.ReadDirFile interface.
+func (d *Dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		entries, err := d.ReadDirFile.ReadDir(-1)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = d.fsys.logicalEntries(d.name, entries)
+		d.read = true
+	}
+
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
+
+// ReadDir implements the fs.ReadDirFS interface. Entries are listed like by
+// Dir, i.e. compressed files without their suffix.
+func (compressed FileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	f, err := compressed.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	d, ok := f.(fs.ReadDirFile)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return d.ReadDir(-1)
+}
+
+// Glob implements the fs.GlobFS interface. The names of compressed files are
+// matched and returned without their suffix.
+func (compressed FileSystem) Glob(pattern string) ([]string, error) {
+	return compressed.globLogical(pattern)
+}
+
+// Sub implements the fs.SubFS interface. Unlike for fs.Sub, the returned FS
+// implements fs.ReadDirFS and fs.GlobFS, see TrimPrefix.
+func (compressed FileSystem) Sub(dir string) (fs.FS, error) {
+	if !fs.ValidPath(dir) {
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: fs.ErrInvalid}
+	}
+	if dir == "." {
+		return compressed, nil
+	}
+	return compressed.TrimPrefix(dir), nil
+}
+
+// logicalEntries returns the entries of the directory dir as seen through the
+// FileSystem. A compressed file is hidden if a plain file of the same name
+// exists, as Open prefers the plain file.
+func (compressed FileSystem) logicalEntries(dir string, entries []fs.DirEntry) []fs.DirEntry {
+	plain := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		plain[e.Name()] = true
+	}
+
+	logical := make([]fs.DirEntry, 0, len(entries))
+	for _, e := range entries {
+		name, ok := compressed.trimDecodable(e.Name())
+		if e.IsDir() || !ok {
+			logical = append(logical, e)
+			continue
+		}
+		// Files stored with several codings are listed once.
+		if plain[name] {
+			continue
+		}
+		plain[name] = true
+		logical = append(logical, DirEntry{DirEntry: e, fsys: compressed, name: path.Join(dir, name)})
+	}
+	sort.Slice(logical, func(i, j int) bool { return logical[i].Name() < logical[j].Name() })
+	return logical
+}
+
+// DirEntry is the directory entry of a compressed file. It describes the
+// decompressed file, consistent with what Open returns for it.
+type DirEntry struct {
+	fs.DirEntry
+	fsys FileSystem
+	// The name of the decompressed file within the FileSystem.
+	name string
+}
+
+// Name implements the fs.DirEntry interface.
+func (e DirEntry) Name() string { return path.Base(e.name) }
+
+// IsDir implements the fs.DirEntry interface.
+func (e DirEntry) IsDir() bool { return false }
+
+// Type implements the fs.DirEntry interface.
+func (e DirEntry) Type() fs.FileMode { return e.DirEntry.Type() }
+
+// Info implements the fs.DirEntry interface. As the size of the decompressed
+// file is reported, this has to decompress it.
+func (e DirEntry) Info() (fs.FileInfo, error) {
+	f, err := e.fsys.Open(e.name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
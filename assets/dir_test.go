@@ -0,0 +1,147 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadDir(t *testing.T) {
+	entries, err := fs.ReadDir(testFS, "testdata/templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if expected := []string{"greeting.tmpl", "page.tmpl"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("entries are wrong, expected %v, got %v", expected, names)
+	}
+}
+
+func TestDirEntries(t *testing.T) {
+	entries, err := fs.ReadDir(testFS, "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		isDir bool
+		size  int64
+	}{
+		"both":         {size: 4},
+		"compressed":   {size: 4},
+		"uncompressed": {size: 4},
+		"templates":    {isDir: true},
+	}
+	seen := map[string]bool{}
+	for _, e := range entries {
+		if seen[e.Name()] {
+			t.Fatalf("duplicate entry %q", e.Name())
+		}
+		seen[e.Name()] = true
+
+		c, ok := cases[e.Name()]
+		if !ok {
+			continue
+		}
+		delete(cases, e.Name())
+
+		if e.IsDir() != c.isDir || e.Type().IsDir() != c.isDir || e.Type().IsRegular() == c.isDir {
+			t.Fatalf("%s: type is wrong, expected dir %v, got %v", e.Name(), c.isDir, e.Type())
+		}
+		info, err := e.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Name() != e.Name() {
+			t.Fatalf("%s: info name is wrong, got %q", e.Name(), info.Name())
+		}
+		if info.IsDir() != c.isDir || info.Mode().Type() != e.Type() {
+			t.Fatalf("%s: info mode is wrong, got %v", e.Name(), info.Mode())
+		}
+		if !c.isDir && info.Size() != c.size {
+			t.Fatalf("%s: size is wrong, expected %d, got %d", e.Name(), c.size, info.Size())
+		}
+	}
+	if len(cases) != 0 {
+		t.Fatalf("missing entries: %v", cases)
+	}
+}
+
+func TestReadDirPaged(t *testing.T) {
+	f, err := testFS.Open("testdata/templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d := f.(fs.ReadDirFile)
+
+	var names []string
+	for {
+		entries, err := d.ReadDir(1)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 {
+			t.Fatalf("expected one entry, got %d", len(entries))
+		}
+		names = append(names, entries[0].Name())
+	}
+	if expected := []string{"greeting.tmpl", "page.tmpl"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("entries are wrong, expected %v, got %v", expected, names)
+	}
+}
+
+func TestFSInterfaces(t *testing.T) {
+	fsys := New(siteFS)
+	if err := fstest.TestFS(fsys, "testdata/site/style.css", "testdata/site/guide/setup/index.html", "testdata/site/app.js"); err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := fsys.Glob("testdata/site/*.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"testdata/site/style.css"}; !reflect.DeepEqual(matches, expected) {
+		t.Fatalf("matches are wrong, expected %v, got %v", expected, matches)
+	}
+	if _, err := fsys.Glob("testdata/site/[*"); err == nil {
+		t.Fatal("expected an error for a malformed pattern")
+	}
+
+	sub, err := fsys.Sub("testdata/site/guide")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(sub, "intro.html", "setup/index.html"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fsys.Sub("../site"); err == nil {
+		t.Fatal("expected an error for an invalid path")
+	}
+
+	if _, err := fsys.ReadDir("testdata/site/app.js"); err == nil {
+		t.Fatal("expected an error reading a file as a directory")
+	}
+}
@@ -14,52 +14,440 @@
 package assets
 
 import (
+	"bufio"
+	"bytes"
 	"compress/gzip"
+	"context"
 	"embed"
+	"errors"
+	"fmt"
 	"io"
 	"io/fs"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const (
 	gzipSuffix = ".gz"
+	zstdSuffix = ".zst"
+	brSuffix   = ".br"
+
+	// gzipOSUnknown is the OS header byte for an unknown operating system.
+	gzipOSUnknown = 255
+
+	// defaultReadBufferSize is the default size of the chunks streaming
+	// files are copied in, matching io.Copy.
+	defaultReadBufferSize = 32 * 1024
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+
+	// gzipReaders pools gzip readers, as each of them holds on to sizable
+	// decompression state.
+	gzipReaders sync.Pool
+	// gzipReadersCreated counts the gzip readers not taken from the pool.
+	gzipReadersCreated atomic.Int64
 )
 
+// getGzipReader returns a gzip reader for r, reusing a pooled one if possible.
+func getGzipReader(r io.Reader) (*gzip.Reader, error) {
+	if gr, ok := gzipReaders.Get().(*gzip.Reader); ok {
+		if err := gr.Reset(r); err != nil {
+			gzipReaders.Put(gr)
+			return nil, err
+		}
+		return gr, nil
+	}
+	gzipReadersCreated.Add(1)
+	return gzip.NewReader(r)
+}
+
+// putGzipReader closes gr and returns it to the pool.
+func putGzipReader(gr *gzip.Reader) {
+	gr.Close()
+	gzipReaders.Put(gr)
+}
+
+// pooledGzipReader is a gzip reader returned to the pool when closed.
+type pooledGzipReader struct {
+	*gzip.Reader
+}
+
+// Close implements the io.Closer interface.
+func (r pooledGzipReader) Close() error {
+	putGzipReader(r.Reader)
+	return nil
+}
+
 type FileSystem struct {
 	embed embed.FS
+	opts  *options
+	// Memoized results of ReadString, keyed by name.
+	strings *sync.Map
+	// Memoized ETags of served content, keyed by stored name.
+	etags *sync.Map
+	// The codings each file is stored with.
+	index *fileIndex
+	// Memoized results of Concat, keyed by the names and ETags of the parts.
+	bundles *sync.Map
 }
 
+// New returns a FileSystem for the given embed FS which only decompresses gzip
+// files, treating files with other suffixes such as ".br" as plain files, and
+// doesn't cache anything. It never fails and scans directories only when
+// first accessed, as it always did. Existing callers of New thus keep their
+// behavior as options are added, and can switch to NewWithOptions once they
+// need one, which also decodes the codings registered with RegisterDecoder.
 func New(fs embed.FS) FileSystem {
-	return FileSystem{fs}
+	compressed := newFileSystem(fs)
+	compressed.opts.gzipOnly = true
+	compressed.index = newIndex(fs, true, compressed.codings())
+	return compressed
 }
 
+// newFileSystem returns a FileSystem for fs without an index.
+func newFileSystem(fs embed.FS) FileSystem {
+	return FileSystem{
+		embed:   fs,
+		opts:    &options{},
+		strings: &sync.Map{},
+		etags:   &sync.Map{},
+		bundles: &sync.Map{},
+	}
+}
+
+// DecodeError is returned when the compressed content of a file can't be
+// decoded.
+type DecodeError struct {
+	// The name of the FileSystem, if set with WithName.
+	FS string
+	// The path of the compressed file within the FileSystem.
+	Path string
+	Err  error
+}
+
+func (e *DecodeError) Error() string {
+	if e.FS != "" {
+		return fmt.Sprintf("assets: %s: decode %s: %v", e.FS, e.Path, e.Err)
+	}
+	return fmt.Sprintf("assets: decode %s: %v", e.Path, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
 // Open implements the fs.FS interface.
 func (compressed FileSystem) Open(path string) (fs.File, error) {
+	return compressed.OpenContext(context.Background(), path)
+}
+
+// OpenContext is like Open, but gives up waiting for a slot to decompress the
+// file once ctx is done, if limited with WithMaxConcurrentDecodes.
+func (compressed FileSystem) OpenContext(ctx context.Context, path string) (fs.File, error) {
+	f, _, err := compressed.openResolved(ctx, path)
+	return f, err
+}
+
+// openResolved is like OpenContext, also returning the name path resolved to,
+// so that callers needn't resolve it again.
+func (compressed FileSystem) openResolved(ctx context.Context, path string) (fs.File, string, error) {
+	name, err := compressed.resolve("open", path)
+	var f fs.File
+	if err == nil {
+		f, err = compressed.open(ctx, name)
+	}
+	if compressed.opts != nil && compressed.opts.accessLog != nil {
+		compressed.opts.accessLog(compressed.fsName(), path, err == nil)
+	}
+	return f, name, err
+}
+
+// WithAccessLog returns a copy of the FileSystem calling log for every call to
+// Open, with the name of the FileSystem set with WithName, if any, the path
+// asked for and whether it could be opened. This helps debugging why files
+// aren't found.
+func (compressed FileSystem) WithAccessLog(log func(fsName, path string, found bool)) FileSystem {
+	opts := &options{}
+	if compressed.opts != nil {
+		*opts = *compressed.opts
+	}
+	opts.accessLog = log
+	compressed.opts = opts
+	return compressed
+}
+
+// open opens the file with the resolved name path.
+func (compressed FileSystem) open(ctx context.Context, path string) (fs.File, error) {
+	// The root is always a directory and never has a compressed variant.
+	if path == "." {
+		f, err := compressed.embed.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		return compressed.wrapDir(path, f), nil
+	}
+
 	// If we have the file in our embed FS, just return that as it could be a dir.
 	var f fs.File
 	if f, err := compressed.embed.Open(path); err == nil {
-		return f, nil
+		return compressed.wrapDir(path, f), nil
 	}
 
-	f, err := compressed.embed.Open(path + gzipSuffix)
+	encoding, err := compressed.decodeEncoding(path)
+	if err != nil {
+		return nil, err
+	}
+	stored := path + suffixFor(encoding)
+	f, err = compressed.embed.Open(stored)
 	if err != nil {
+		// Report the name that was asked for, not the compressed one.
+		if pathErr, ok := err.(*fs.PathError); ok {
+			pathErr.Path = path
+		}
 		return f, err
 	}
+	if compressed.opts != nil && (compressed.opts.streaming || compressed.opts.streamThreshold > 0) {
+		size, err := compressed.streamSize(path, encoding, f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if compressed.opts.streaming || size < 0 || size >= compressed.opts.streamThreshold {
+			return compressed.openStream(path, encoding, f, size)
+		}
+	}
+	cache := compressed.cache()
+	if cache != nil {
+		if c, ok := cache.get(compressed.fsName(), path); ok {
+			return &File{file: f, content: c}, nil
+		}
+	}
+	release, err := compressed.acquireDecode(ctx)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	defer release()
 	// Read the decompressed content into a buffer.
-	gr, err := gzip.NewReader(f)
+	done := compressed.startTiming(path)
+	dr, err := newDecoder(encoding, f)
 	if err != nil {
-		return f, err
+		f.Close()
+		return nil, compressed.decodeError(stored, err)
 	}
-	defer gr.Close()
+	defer dr.Close()
 
-	c, err := io.ReadAll(gr)
+	c, err := io.ReadAll(dr)
 	if err != nil {
-		return f, err
+		f.Close()
+		return nil, compressed.decodeError(stored, err)
+	}
+	done()
+	if cache != nil {
+		cache.add(compressed.fsName(), path, c)
 	}
 	// Wrap everything in our custom File.
 	return &File{file: f, content: c}, nil
 }
 
+func (compressed FileSystem) decodeError(path string, err error) error {
+	return &DecodeError{FS: compressed.fsName(), Path: path, Err: err}
+}
+
+// ReadString returns the decompressed content of the named file as a string.
+// As embedded content never changes, the result is memoized, so that repeated
+// reads of the same file, e.g. a template, don't allocate a new copy each time.
+func (compressed FileSystem) ReadString(name string) (string, error) {
+	if compressed.strings != nil {
+		if s, ok := compressed.strings.Load(name); ok {
+			return s.(string), nil
+		}
+	}
+
+	content, err := fs.ReadFile(compressed, name)
+	if err != nil {
+		return "", err
+	}
+	s := string(content)
+	if compressed.strings != nil {
+		compressed.strings.Store(name, s)
+	}
+	return s, nil
+}
+
+// ReadFileInto decompresses the named file into buf, returning the number of
+// bytes read. If buf is too small, io.ErrShortBuffer is returned along with
+// the size of the decompressed file, so that the caller can retry with a large
+// enough buffer. The size can also be learned in advance from Stat. Unlike
+// ReadFile, this doesn't allocate a buffer for the content.
+func (compressed FileSystem) ReadFileInto(path string, buf []byte) (int, error) {
+	path, err := compressed.resolve("read", path)
+	if err != nil {
+		return 0, err
+	}
+
+	if f, err := compressed.embed.Open(path); err == nil {
+		defer f.Close()
+		stat, err := f.Stat()
+		if err != nil {
+			return 0, err
+		}
+		if stat.IsDir() {
+			return 0, &fs.PathError{Op: "read", Path: path, Err: errors.New("is a directory")}
+		}
+		if stat.Size() > int64(len(buf)) {
+			return int(stat.Size()), io.ErrShortBuffer
+		}
+		return io.ReadFull(f, buf[:stat.Size()])
+	}
+
+	encoding, err := compressed.decodeEncoding(path)
+	if err != nil {
+		return 0, err
+	}
+	stored := path + suffixFor(encoding)
+	f, err := compressed.embed.Open(stored)
+	if err != nil {
+		if pathErr, ok := err.(*fs.PathError); ok {
+			pathErr.Path = path
+		}
+		return 0, err
+	}
+	defer f.Close()
+	// Avoid decompressing files known to be too large, e.g. from their trailer.
+	if size, err := compressed.streamSize(path, encoding, f); err == nil && size > int64(len(buf)) {
+		return int(size), io.ErrShortBuffer
+	}
+
+	dr, err := newDecoder(encoding, f)
+	if err != nil {
+		return 0, compressed.decodeError(stored, err)
+	}
+	defer dr.Close()
+	n, err := io.ReadFull(dr, buf)
+	switch err {
+	case io.EOF, io.ErrUnexpectedEOF:
+		return n, nil
+	case nil:
+	default:
+		return n, compressed.decodeError(stored, err)
+	}
+
+	// The buffer is full, find out whether there is more.
+	rest, err := io.Copy(io.Discard, dr)
+	if err != nil {
+		return n, compressed.decodeError(stored, err)
+	}
+	if rest > 0 {
+		return n + int(rest), io.ErrShortBuffer
+	}
+	return n, nil
+}
+
+// Comment returns the comment stored in the gzip header of the named file, e.g.
+// to record which commit it was built from, without decompressing its
+// content. An empty string is returned for files without a comment, including
+// those stored uncompressed. Files only stored with other codings than gzip
+// have no header to take a comment from, for which an error is returned.
+func (compressed FileSystem) Comment(path string) (string, error) {
+	path, err := compressed.resolve("open", path)
+	if err != nil {
+		return "", err
+	}
+	f, err := compressed.embed.Open(path + gzipSuffix)
+	if err != nil {
+		if stat, err := fs.Stat(compressed.embed, path); err == nil && !stat.IsDir() {
+			return "", nil
+		}
+		for _, se := range compressed.codings() {
+			if compressed.hasEncoding(path, se.encoding) {
+				return "", &fs.PathError{Op: "comment", Path: path, Err: fmt.Errorf("no gzip header, only stored with %s", se.encoding)}
+			}
+		}
+		return "", &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+	}
+	defer f.Close()
+	// Creating the reader only parses the header.
+	gr, err := getGzipReader(f)
+	if err != nil {
+		return "", compressed.decodeError(path+gzipSuffix, err)
+	}
+	defer putGzipReader(gr)
+	return gr.Comment, nil
+}
+
+// Recompress writes the content read from src to dst as a gzip stream with the
+// given compression level. If src is gzip compressed itself, it is decompressed
+// first, retaining the name and comment of its header. The modification time
+// is always zeroed and the OS byte set to "unknown", so that compressing the
+// same content always results in the same output, regardless of when and where
+// it happened. This makes the output suitable for committing generated assets.
+func Recompress(dst io.Writer, src io.Reader, level int) error {
+	gw, err := gzip.NewWriterLevel(dst, level)
+	if err != nil {
+		return err
+	}
+
+	br := bufio.NewReader(src)
+	if magic, err := br.Peek(len(gzipMagic)); err == nil && bytes.Equal(magic, gzipMagic) {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		gw.Name = gr.Name
+		gw.Comment = gr.Comment
+		src = gr
+	} else {
+		src = br
+	}
+	gw.ModTime = time.Time{}
+	gw.OS = gzipOSUnknown
+
+	if _, err := io.Copy(gw, src); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// streamSize returns the decompressed size of the file f, stored with the
+// encoding, without decoding it, or -1 if it is unknown.
+func (compressed FileSystem) streamSize(path, encoding string, f fs.File) (int64, error) {
+	if size, ok := compressed.knownSize(path); ok {
+		return size, nil
+	}
+	if encoding != "gzip" {
+		return -1, nil
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		return -1, err
+	}
+	size, err := gzipSize(f, stat.Size())
+	if err != nil {
+		return -1, compressed.decodeError(path+gzipSuffix, err)
+	}
+	return size, nil
+}
+
+// openStream returns a File decompressing f, stored with the encoding, on
+// the fly. Its Stat reports the given size, -1 if unknown.
+func (compressed FileSystem) openStream(path, encoding string, f fs.File, size int64) (fs.File, error) {
+	dr, err := newDecoder(encoding, f)
+	if err != nil {
+		f.Close()
+		return nil, compressed.decodeError(path+suffixFor(encoding), err)
+	}
+	bufSize := defaultReadBufferSize
+	if compressed.opts != nil && compressed.opts.readBufferSize > 0 {
+		bufSize = compressed.opts.readBufferSize
+	}
+	return &File{file: f, reader: dr, size: size, bufSize: bufSize}, nil
+}
+
+// File is a file of the FileSystem.
 type File struct {
 	// The underlying file.
 	file fs.File
@@ -67,6 +455,14 @@ type File struct {
 	content []byte
 	// Offset for calls to Read().
 	offset int
+	closed bool
+
+	// In streaming mode, the reader decompressing the underlying file, the
+	// decompressed size as recorded in its trailer, or -1 if unknown, and the
+	// size of the chunks read from the reader by WriteTo.
+	reader  io.ReadCloser
+	size    int64
+	bufSize int
 }
 
 // Stat implements the fs.File interface.
@@ -75,24 +471,131 @@ func (f File) Stat() (fs.FileInfo, error) {
 	if err != nil {
 		return stat, err
 	}
+	if f.reader != nil {
+		return FileInfo{stat, f.size}, nil
+	}
 	return FileInfo{stat, int64(len(f.content))}, nil
 }
 
 // Read implements the fs.File interface.
 func (f *File) Read(buf []byte) (int, error) {
-	if len(buf) > len(f.content)-f.offset {
-		buf = buf[0:len(f.content[f.offset:])]
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if f.reader != nil {
+		return f.reader.Read(buf)
+	}
+	if f.offset >= len(f.content) {
+		return 0, io.EOF
 	}
 	n := copy(buf, f.content[f.offset:])
-	if n == len(f.content)-f.offset {
+	f.offset += n
+	if f.offset == len(f.content) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Seek implements the io.Seeker interface. Files opened in streaming mode
+// can't seek.
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if f.reader != nil {
+		return 0, errors.New("assets: seek: file is opened in streaming mode")
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += int64(f.offset)
+	case io.SeekEnd:
+		offset += int64(len(f.content))
+	default:
+		return 0, errors.New("assets: seek: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("assets: seek: negative position")
+	}
+	f.offset = int(offset)
+	return offset, nil
+}
+
+// ReadAt implements the io.ReaderAt interface. Files opened in streaming mode
+// don't support it.
+func (f *File) ReadAt(buf []byte, off int64) (int, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if f.reader != nil {
+		return 0, errors.New("assets: read at: file is opened in streaming mode")
+	}
+	if off < 0 {
+		return 0, errors.New("assets: read at: negative offset")
+	}
+	if off >= int64(len(f.content)) {
+		return 0, io.EOF
+	}
+	n := copy(buf, f.content[off:])
+	if n < len(buf) {
 		return n, io.EOF
 	}
-	f.offset += n
 	return n, nil
 }
 
+// WriteTo implements the io.WriterTo interface. Streaming files are copied in
+// chunks of the size set with WithReadBufferSize.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if f.reader == nil {
+		if f.offset >= len(f.content) {
+			return 0, nil
+		}
+		n, err := w.Write(f.content[f.offset:])
+		f.offset += n
+		return int64(n), err
+	}
+
+	bufSize := f.bufSize
+	if bufSize <= 0 {
+		bufSize = defaultReadBufferSize
+	}
+	buf := make([]byte, bufSize)
+	var written int64
+	for {
+		n, err := f.reader.Read(buf)
+		if n > 0 {
+			m, werr := w.Write(buf[:n])
+			written += int64(m)
+			if werr != nil {
+				return written, werr
+			}
+			if m != n {
+				return written, io.ErrShortWrite
+			}
+		}
+		if err == io.EOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+}
+
 // Close implements the fs.File interface.
-func (f File) Close() error {
+func (f *File) Close() error {
+	// Closing more than once is a no-op.
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	if f.reader != nil {
+		f.reader.Close()
+		f.reader = nil
+	}
 	return f.file.Close()
 }
 
@@ -104,7 +607,12 @@ type FileInfo struct {
 // Name implements the fs.FileInfo interface.
 func (fi FileInfo) Name() string {
 	name := fi.fi.Name()
-	return name[:len(name)-len(gzipSuffix)]
+	for _, se := range storedEncodings {
+		if strings.HasSuffix(name, se.suffix) {
+			return strings.TrimSuffix(name, se.suffix)
+		}
+	}
+	return name
 }
 
 // Size implements the fs.FileInfo interface.
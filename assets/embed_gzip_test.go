@@ -14,16 +14,41 @@
 package assets
 
 import (
+	"bytes"
+	"compress/gzip"
 	"embed"
+	"errors"
 	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 //go:embed testdata
 var EmbedFS embed.FS
 
-var testFS = New(EmbedFS)
+// testFS recognizes all stored codings, unlike a FileSystem returned by New.
+var testFS = mustNewWithOptions(EmbedFS)
+
+// brokenEmbedFS holds a corrupt gzip file. It is kept apart from EmbedFS, so
+// that all files of testFS can be opened.
+//
+//go:embed testdata_broken
+var brokenEmbedFS embed.FS
+
+var brokenFS = mustNewWithOptions(brokenEmbedFS)
+
+func mustNewWithOptions(fsys embed.FS, opts ...Option) FileSystem {
+	compressed, err := NewWithOptions(fsys, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return compressed
+}
 
 func TestFS(t *testing.T) {
 	cases := []struct {
@@ -91,3 +116,540 @@ func TestFS(t *testing.T) {
 		})
 	}
 }
+
+func TestOpenRoot(t *testing.T) {
+	f, err := testFS.Open(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, ok := f.(fs.ReadDirFile); !ok {
+		t.Fatalf("expected root to be a fs.ReadDirFile, got %T", f)
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stat.IsDir() {
+		t.Fatal("expected root to be a directory")
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	for _, path := range []string{"", "/testdata", "testdata/", "testdata/../testdata"} {
+		_, err := testFS.Open(path)
+		if !errors.Is(err, fs.ErrInvalid) {
+			t.Fatalf("expected fs.ErrInvalid for %q, got %v", path, err)
+		}
+		var pathErr *fs.PathError
+		if !errors.As(err, &pathErr) {
+			t.Fatalf("expected *fs.PathError for %q, got %T", path, err)
+		}
+	}
+}
+
+func TestWalkDir(t *testing.T) {
+	var paths []string
+	err := fs.WalkDir(testFS, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			// Every file must be openable by the name it was walked with.
+			f, err := testFS.Open(path)
+			if err != nil {
+				return err
+			}
+			f.Close()
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	walked := map[string]bool{}
+	for _, p := range paths {
+		walked[p] = true
+	}
+	for _, p := range []string{
+		".",
+		"testdata",
+		"testdata/both",
+		"testdata/compressed",
+		"testdata/templates/greeting.tmpl",
+		"testdata/uncompressed",
+	} {
+		if !walked[p] {
+			t.Fatalf("expected %q to be walked, got %v", p, paths)
+		}
+	}
+}
+
+func TestRecompress(t *testing.T) {
+	// Compress the same content twice, with differing headers as produced by
+	// different tools at different times.
+	var inputs [][]byte
+	for i, mtime := range []time.Time{time.Unix(1, 0), time.Unix(1700000000, 0)} {
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		gw.Name = "file.txt"
+		gw.ModTime = mtime
+		gw.OS = byte(i)
+		if _, err := io.WriteString(gw, "foo\n"); err != nil {
+			t.Fatal(err)
+		}
+		if err := gw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, buf.Bytes())
+	}
+	if bytes.Equal(inputs[0], inputs[1]) {
+		t.Fatal("expected inputs to differ")
+	}
+
+	var outputs [][]byte
+	for _, input := range append(inputs, inputs[0]) {
+		var buf bytes.Buffer
+		if err := Recompress(&buf, bytes.NewReader(input), gzip.BestCompression); err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, buf.Bytes())
+	}
+	for i, output := range outputs[1:] {
+		if !bytes.Equal(outputs[0], output) {
+			t.Fatalf("output %d differs from first output", i+1)
+		}
+	}
+
+	gr, err := gzip.NewReader(bytes.NewReader(outputs[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gr.Name != "file.txt" {
+		t.Fatalf("name is wrong, expected %q, got %q", "file.txt", gr.Name)
+	}
+	if !gr.ModTime.IsZero() {
+		t.Fatalf("expected zero modification time, got %v", gr.ModTime)
+	}
+	if gr.OS != gzipOSUnknown {
+		t.Fatalf("OS is wrong, expected %d, got %d", gzipOSUnknown, gr.OS)
+	}
+	content, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "foo\n" {
+		t.Fatalf("content is wrong, expected %q, got %q", "foo\n", string(content))
+	}
+}
+
+func TestRecompressUncompressed(t *testing.T) {
+	var first, second bytes.Buffer
+	for _, buf := range []*bytes.Buffer{&first, &second} {
+		if err := Recompress(buf, strings.NewReader("foo\n"), gzip.DefaultCompression); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Fatal("expected identical output across runs")
+	}
+}
+
+func TestRecompressInvalidLevel(t *testing.T) {
+	if err := Recompress(io.Discard, strings.NewReader("foo\n"), 42); err == nil {
+		t.Fatal("expected an error for an invalid compression level")
+	}
+}
+
+func TestReadString(t *testing.T) {
+	fsys := New(EmbedFS)
+	for _, path := range []string{"testdata/compressed", "testdata/uncompressed"} {
+		s, err := fsys.ReadString(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != "foo\n" {
+			t.Fatalf("content of %s is wrong, expected %q, got %q", path, "foo\n", s)
+		}
+
+		allocs := testing.AllocsPerRun(10, func() {
+			if _, err := fsys.ReadString(path); err != nil {
+				t.Fatal(err)
+			}
+		})
+		if allocs != 0 {
+			t.Fatalf("expected memoized reads of %s not to allocate, got %v allocations", path, allocs)
+		}
+	}
+
+	if _, err := fsys.ReadString("testdata/missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected fs.ErrNotExist, got %v", err)
+	}
+}
+
+func TestGzipReaderPool(t *testing.T) {
+	const opens = 100
+	created := gzipReadersCreated.Load()
+	for i := 0; i < opens; i++ {
+		f, err := testFS.Open("testdata/compressed")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The pool may drop readers at any time, so only check that most of
+	// them got reused.
+	if n := gzipReadersCreated.Load() - created; n > opens/2 {
+		t.Fatalf("expected gzip readers to be reused, %d out of %d were created", n, opens)
+	}
+}
+
+func TestCloseTwice(t *testing.T) {
+	f, err := testFS.Open("testdata/compressed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("expected closing twice to succeed, got %v", err)
+	}
+}
+
+func TestReadFileInto(t *testing.T) {
+	for _, path := range []string{"testdata/compressed", "testdata/uncompressed", "testdata/variants/data.txt"} {
+		t.Run(path, func(t *testing.T) {
+			expected, err := testFS.ReadString(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			buf := make([]byte, 64)
+			n, err := testFS.ReadFileInto(path, buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(buf[:n]) != expected {
+				t.Fatalf("content is wrong, expected %q, got %q", expected, string(buf[:n]))
+			}
+
+			// Exactly fitting buffer.
+			buf = make([]byte, len(expected))
+			if n, err = testFS.ReadFileInto(path, buf); err != nil || n != len(expected) {
+				t.Fatalf("expected %d bytes to be read, got %d, %v", len(expected), n, err)
+			}
+
+			n, err = testFS.ReadFileInto(path, make([]byte, 2))
+			if err != io.ErrShortBuffer {
+				t.Fatalf("expected io.ErrShortBuffer, got %v", err)
+			}
+			if n != len(expected) {
+				t.Fatalf("expected the needed size %d, got %d", len(expected), n)
+			}
+		})
+	}
+
+	if _, err := testFS.ReadFileInto("testdata/missing", nil); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected fs.ErrNotExist, got %v", err)
+	}
+	if _, err := testFS.ReadFileInto("testdata", make([]byte, 64)); err == nil {
+		t.Fatal("expected an error for a directory")
+	}
+
+	// Files only stored with other codings are read with their decoders.
+	gzipAsBrotli(t)
+	fsys := newDecodersFS()
+	for path, expected := range map[string]string{
+		"testdata/decoders/only.txt": "br only\n",
+		"testdata/decoders/both.txt": "br\n",
+	} {
+		buf := make([]byte, 64)
+		n, err := fsys.ReadFileInto(path, buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf[:n]) != expected {
+			t.Fatalf("content of %s is wrong, expected %q, got %q", path, expected, buf[:n])
+		}
+		if n, err := fsys.ReadFileInto(path, make([]byte, 1)); err != io.ErrShortBuffer || n != len(expected) {
+			t.Fatalf("expected io.ErrShortBuffer with size %d, got %d, %v", len(expected), n, err)
+		}
+	}
+}
+
+func TestReadFileIntoMultistream(t *testing.T) {
+	// The trailer of a multi-member file only records the size of the last
+	// member, so the actual size has to be found by decompressing.
+	f, err := testFS.Open("testdata/multistream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := testFS.ReadFileInto("testdata/multistream", make([]byte, len(expected)-1))
+	if err != io.ErrShortBuffer || n != len(expected) {
+		t.Fatalf("expected io.ErrShortBuffer with size %d, got %d, %v", len(expected), n, err)
+	}
+	buf := make([]byte, len(expected))
+	if n, err := testFS.ReadFileInto("testdata/multistream", buf); err != nil || !bytes.Equal(buf[:n], expected) {
+		t.Fatalf("content is wrong, got %q, %v", buf[:n], err)
+	}
+}
+
+func TestComment(t *testing.T) {
+	for path, expected := range map[string]string{
+		"testdata/provenance/commented.txt": "commit 3f9a1c2",
+		"testdata/compressed":               "",
+		"testdata/uncompressed":             "",
+	} {
+		comment, err := testFS.Comment(path)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", path, err)
+		}
+		if comment != expected {
+			t.Fatalf("comment of %s is wrong, expected %q, got %q", path, expected, comment)
+		}
+	}
+
+	for _, path := range []string{"testdata/missing", "testdata"} {
+		if _, err := testFS.Comment(path); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("expected fs.ErrNotExist for %s, got %v", path, err)
+		}
+	}
+	_, err := newDecodersFS().Comment("testdata/decoders/only.txt")
+	if err == nil || errors.Is(err, fs.ErrNotExist) || !strings.Contains(err.Error(), "no gzip header") {
+		t.Fatalf("expected an error for a file without gzip header, got %v", err)
+	}
+	var decodeErr *DecodeError
+	if _, err := brokenFS.Comment("testdata_broken/corrupt"); !errors.As(err, &decodeErr) {
+		t.Fatalf("expected a DecodeError, got %v", err)
+	}
+}
+
+func TestCloseDoesNotAffectOtherFiles(t *testing.T) {
+	first, err := testFS.Open("testdata/compressed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+	second, err := testFS.Open("testdata/variants/data.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+
+	// Using the closed File again must neither panic nor touch the other one.
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.Stat(); err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "variant content\n" {
+		t.Fatalf("content is wrong, got %q", content)
+	}
+}
+
+// BenchmarkOpen reports the allocations of opening a compressed file.
+func BenchmarkOpen(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		f, err := testFS.Open("testdata/compressed")
+		if err != nil {
+			b.Fatal(err)
+		}
+		f.Close()
+	}
+}
+
+func TestWithAccessLog(t *testing.T) {
+	type access struct {
+		path  string
+		found bool
+	}
+	var accesses []access
+	fsys := testFS.WithAccessLog(func(fsName, path string, found bool) {
+		if fsName != "" {
+			t.Errorf("expected no FileSystem name, got %q", fsName)
+		}
+		accesses = append(accesses, access{path, found})
+	})
+
+	for _, path := range []string{"testdata/compressed", "testdata/uncompressed", "testdata/missing", "/invalid", "testdata"} {
+		if f, err := fsys.Open(path); err == nil {
+			f.Close()
+		}
+	}
+	expected := []access{
+		{"testdata/compressed", true},
+		{"testdata/uncompressed", true},
+		{"testdata/missing", false},
+		{"/invalid", false},
+		{"testdata", true},
+	}
+	if !reflect.DeepEqual(accesses, expected) {
+		t.Fatalf("accesses are wrong, expected %v, got %v", expected, accesses)
+	}
+
+	// The original FileSystem doesn't log.
+	accesses = nil
+	if _, err := testFS.Open("testdata/compressed"); err != nil {
+		t.Fatal(err)
+	}
+	if len(accesses) != 0 {
+		t.Fatalf("expected no accesses to be logged, got %v", accesses)
+	}
+}
+
+// For content only stored gzip compressed, New and NewWithOptions agree.
+func TestNewMatchesNewWithOptions(t *testing.T) {
+	withOptions, err := NewWithOptions(siteFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := New(siteFS)
+
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		expected, err := fs.ReadFile(withOptions, name)
+		if err != nil {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(content, expected) {
+			t.Errorf("content of %s is wrong, expected %q, got %q", name, expected, content)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Broken decoders only make NewWithOptions fail.
+	registerTestDecoder(t, "zstd", func(r io.Reader) (io.ReadCloser, error) {
+		return nil, errors.New("zstd support not compiled in")
+	})
+	if _, err := NewWithOptions(siteFS); err == nil {
+		t.Fatal("expected an error for the broken decoder")
+	}
+	if _, err := fs.ReadFile(New(siteFS), "testdata/site/style.css"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSeekReadAt(t *testing.T) {
+	f, err := testFS.Open("testdata/compressed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sf := f.(*File)
+
+	buf := make([]byte, 2)
+	if n, err := sf.Read(buf); n != 2 || err != nil {
+		t.Fatalf("expected to read 2 bytes, got %d, %v", n, err)
+	}
+	buf = make([]byte, 8)
+	if n, err := sf.Read(buf); string(buf[:n]) != "o\n" || err != io.EOF {
+		t.Fatalf("expected the rest and io.EOF, got %q, %v", buf[:n], err)
+	}
+	// Reading at the end doesn't start over.
+	if n, err := sf.Read(buf); n != 0 || err != io.EOF {
+		t.Fatalf("expected io.EOF at the end, got %d, %v", n, err)
+	}
+
+	for _, c := range []struct {
+		offset   int64
+		whence   int
+		expected string
+	}{
+		{offset: 1, whence: io.SeekStart, expected: "oo\n"},
+		{offset: -1, whence: io.SeekEnd, expected: "\n"},
+		// Relative to the end, where the previous read stopped.
+		{offset: -2, whence: io.SeekCurrent, expected: "o\n"},
+		{offset: 10, whence: io.SeekStart, expected: ""},
+	} {
+		if _, err := sf.Seek(c.offset, c.whence); err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(sf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != c.expected {
+			t.Fatalf("seek %d from %d: expected %q, got %q", c.offset, c.whence, c.expected, content)
+		}
+	}
+	if _, err := sf.Seek(-1, io.SeekStart); err == nil {
+		t.Fatal("expected an error for a negative position")
+	}
+
+	n, err := sf.ReadAt(buf[:2], 1)
+	if string(buf[:n]) != "oo" || err != nil {
+		t.Fatalf("expected to read %q, got %q, %v", "oo", buf[:n], err)
+	}
+	n, err = sf.ReadAt(buf, 2)
+	if string(buf[:n]) != "o\n" || err != io.EOF {
+		t.Fatalf("expected to read %q and io.EOF, got %q, %v", "o\n", buf[:n], err)
+	}
+
+	streaming, err := NewWithOptions(EmbedFS, WithStreaming())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err = streaming.Open("testdata/compressed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.(io.Seeker).Seek(0, io.SeekStart); err == nil {
+		t.Fatal("expected an error seeking a streaming file")
+	}
+	if _, err := f.(io.ReaderAt).ReadAt(buf, 0); err == nil {
+		t.Fatal("expected an error reading a streaming file at an offset")
+	}
+}
+
+func TestFileServerRange(t *testing.T) {
+	handler := http.FileServer(http.FS(testFS))
+
+	req := httptest.NewRequest(http.MethodGet, "/testdata/compressed", nil)
+	req.Header.Set("Range", "bytes=1-2")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("status is wrong, expected %d, got %d", http.StatusPartialContent, rec.Code)
+	}
+	if rec.Body.String() != "oo" {
+		t.Fatalf("body is wrong, expected %q, got %q", "oo", rec.Body.String())
+	}
+	if cl := rec.Header().Get("Content-Length"); cl != "2" {
+		t.Fatalf("content length is wrong, expected 2, got %s", cl)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/testdata/compressed", nil))
+	if rec.Body.String() != "foo\n" || rec.Header().Get("Content-Length") != "4" {
+		t.Fatalf("expected the decompressed content, got %q with length %s", rec.Body.String(), rec.Header().Get("Content-Length"))
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"mime"
+)
+
+// ContentTypeError is returned by OpenExpect when a file doesn't have the
+// expected content type.
+type ContentTypeError struct {
+	// The name of the FileSystem, if set with WithName.
+	FS   string
+	Path string
+	// The expected and the actual media type, without parameters.
+	Want string
+	Got  string
+}
+
+func (e *ContentTypeError) Error() string {
+	if e.FS != "" {
+		return fmt.Sprintf("assets: %s: %s has content type %s, expected %s", e.FS, e.Path, e.Got, e.Want)
+	}
+	return fmt.Sprintf("assets: %s has content type %s, expected %s", e.Path, e.Got, e.Want)
+}
+
+// OpenExpect is like Open, but fails with a ContentTypeError unless the media
+// type of the file, as determined from its extension or, failing that, by
+// sniffing its content, is wantContentType. Parameters like charset are
+// ignored on both sides. This catches misplaced files early, e.g. in startup
+// checks.
+func (compressed FileSystem) OpenExpect(path, wantContentType string) (fs.File, error) {
+	want, _, err := mime.ParseMediaType(wantContentType)
+	if err != nil {
+		return nil, fmt.Errorf("assets: invalid content type %q: %w", wantContentType, err)
+	}
+	f, name, err := compressed.openResolved(context.Background(), path)
+	if err != nil {
+		return nil, err
+	}
+
+	ctype := typeByExtension(name)
+	if ctype == "" {
+		sniffed, sf, err := sniffContentType(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		ctype, f = sniffed, sf
+	}
+	got, _, err := mime.ParseMediaType(ctype)
+	if err != nil {
+		got = ctype
+	}
+	if got != want {
+		f.Close()
+		return nil, &ContentTypeError{FS: compressed.fsName(), Path: name, Want: want, Got: got}
+	}
+	return f, nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"reflect"
+	"testing"
+)
+
+func TestOpenExpect(t *testing.T) {
+	for path, want := range map[string]string{
+		"testdata/site/guide/intro.html": "text/html",
+		"testdata/site/app.js":           "text/javascript; charset=utf-8",
+		// Sniffed from the content.
+		"testdata/compressed":                  "text/plain",
+		"testdata/images/pixel.png":            "image/png",
+		"testdata/site/guide/setup/index.html": "TEXT/HTML",
+	} {
+		f, err := testFS.OpenExpect(path, want)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %v", path, err)
+		}
+		if _, err := io.ReadAll(f); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+	}
+}
+
+func TestOpenExpectOpensOnce(t *testing.T) {
+	var calls []string
+	for _, streaming := range []bool{false, true} {
+		opts := []Option{
+			WithAliases(map[string]string{"latest": "testdata/compressed"}),
+			WithFaultInjector(func(path string) error {
+				calls = append(calls, path)
+				return nil
+			}),
+		}
+		if streaming {
+			opts = append(opts, WithStreaming())
+		}
+		fsys, err := NewWithOptions(EmbedFS, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		calls = nil
+		f, err := fsys.OpenExpect("latest", "text/plain")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The sniffed bytes are still read.
+		content, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		if string(content) != "foo\n" {
+			t.Fatalf("content is wrong, expected %q, got %q", "foo\n", content)
+		}
+		if expected := []string{"testdata/compressed"}; !reflect.DeepEqual(calls, expected) {
+			t.Fatalf("expected the file to be resolved once, got %v", calls)
+		}
+	}
+}
+
+func TestOpenExpectMismatch(t *testing.T) {
+	fsys, err := NewWithOptions(EmbedFS, WithName("ui"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = fsys.OpenExpect("testdata/site/guide/intro.html", "application/json")
+	var ctErr *ContentTypeError
+	if !errors.As(err, &ctErr) {
+		t.Fatalf("expected a ContentTypeError, got %v", err)
+	}
+	if ctErr.Got != "text/html" || ctErr.Want != "application/json" {
+		t.Fatalf("error is wrong, got %+v", ctErr)
+	}
+	if expected := "assets: ui: testdata/site/guide/intro.html has content type text/html, expected application/json"; err.Error() != expected {
+		t.Fatalf("error message is wrong, expected %q, got %q", expected, err.Error())
+	}
+
+	if _, err := testFS.OpenExpect("testdata/missing.json", "application/json"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected fs.ErrNotExist, got %v", err)
+	}
+	if _, err := testFS.OpenExpect("testdata/compressed", ""); err == nil {
+		t.Fatal("expected an error for an invalid content type")
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"path"
+	"strings"
+)
+
+// fingerprintLen is the number of hex characters of the content hash included
+// in fingerprinted names.
+const fingerprintLen = 8
+
+// FingerprintedName returns the name of the file with a prefix of the hex
+// encoded hash of its decompressed content inserted before its extension, e.g.
+// "static/app.3f9a1c2b.js" for "static/app.js". FileSystems created with
+// WithManifestPath resolve such names to the file, so that they can be served
+// with far-future caching headers, see WithImmutablePattern.
+func (compressed FileSystem) FingerprintedName(path string) (string, error) {
+	name, err := compressed.resolvePath("open", path)
+	if err != nil {
+		return "", err
+	}
+	sum, err := compressed.memoizedContentHash(name)
+	if err != nil {
+		return "", err
+	}
+	return fingerprintedName(path, sum), nil
+}
+
+// memoizedContentHash is like contentHash, remembering the result, as the
+// content never changes.
+func (compressed FileSystem) memoizedContentHash(name string) (string, error) {
+	key := name + "\x00hash"
+	if sum, ok := compressed.knownETag(key); ok {
+		return sum, nil
+	}
+	sum, err := compressed.contentHash(name)
+	if err != nil {
+		return "", err
+	}
+	if compressed.etags != nil {
+		compressed.etags.Store(key, sum)
+	}
+	return sum, nil
+}
+
+// fingerprintedName inserts the prefix of the hex encoded hash sum into name.
+func fingerprintedName(name, sum string) string {
+	dir, base := path.Split(name)
+	ext := path.Ext(base)
+	if ext == base {
+		// Dot files like ".htaccess" have no extension.
+		ext = ""
+	}
+	return dir + strings.TrimSuffix(base, ext) + "." + fingerprintOf(sum) + ext
+}
+
+// fingerprintOf returns the prefix of the hex encoded hash sum used in
+// fingerprinted names.
+func fingerprintOf(sum string) string {
+	if len(sum) > fingerprintLen {
+		return sum[:fingerprintLen]
+	}
+	return sum
+}
+
+// splitFingerprint returns the name without its fingerprint, and the
+// fingerprint, if name looks like it was returned by fingerprintedName.
+func splitFingerprint(name string) (string, string, bool) {
+	dir, base := path.Split(name)
+	ext := path.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	if ext != "" && isFingerprint(ext[1:]) && stem != "" {
+		// A name without extension, like "LICENSE.3f9a1c2b".
+		return dir + stem, ext[1:], true
+	}
+	i := strings.LastIndexByte(stem, '.')
+	if i <= 0 || !isFingerprint(stem[i+1:]) {
+		return "", "", false
+	}
+	return dir + stem[:i] + ext, stem[i+1:], true
+}
+
+// isFingerprint reports whether s is a valid fingerprint.
+func isFingerprint(s string) bool {
+	if len(s) != fingerprintLen {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveFingerprint returns the file the fingerprinted name refers to, if
+// fingerprinted names are resolved and its fingerprint matches the content,
+// and name itself otherwise.
+func (compressed FileSystem) resolveFingerprint(name string) string {
+	if compressed.opts == nil || compressed.opts.manifest == nil || compressed.isFile(name) {
+		return name
+	}
+	original, fingerprint, ok := splitFingerprint(name)
+	if !ok {
+		return name
+	}
+	target, _ := compressed.resolveAlias(original)
+	target = compressed.foldCase(target)
+	if !compressed.isFile(target) {
+		return name
+	}
+	sum, err := compressed.memoizedContentHash(target)
+	if err != nil || fingerprintOf(sum) != fingerprint {
+		return name
+	}
+	return target
+}
@@ -0,0 +1,84 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestFingerprintedName(t *testing.T) {
+	fsys, err := NewWithOptions(templatesFS, WithManifestPath("/asset-manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, err := fsys.FingerprintedName("testdata/templates/page.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "testdata/templates/page.ff962c9e.tmpl"; name != expected {
+		t.Fatalf("name is wrong, expected %q, got %q", expected, name)
+	}
+	content, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := fs.ReadFile(fsys, "testdata/templates/page.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != string(expected) {
+		t.Fatalf("content is wrong, expected %q, got %q", expected, content)
+	}
+
+	if _, err := fsys.Open("testdata/templates/greeting.ff962c9e.tmpl"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected a mismatching fingerprint not to exist, got %v", err)
+	}
+	// Without WithManifestPath, fingerprinted names aren't resolved.
+	if _, err := New(templatesFS).Open(name); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected fingerprinted names not to be resolved, got %v", err)
+	}
+	if _, err := fsys.FingerprintedName("testdata/templates/missing.tmpl"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected an error for a missing file, got %v", err)
+	}
+}
+
+func TestSplitFingerprint(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		original    string
+		fingerprint string
+		ok          bool
+	}{
+		{name: "static/app.3f9a1c2b.js", original: "static/app.js", fingerprint: "3f9a1c2b", ok: true},
+		{name: "app.min.3f9a1c2b.js", original: "app.min.js", fingerprint: "3f9a1c2b", ok: true},
+		{name: "LICENSE.3f9a1c2b", original: "LICENSE", fingerprint: "3f9a1c2b", ok: true},
+		{name: ".htaccess.3f9a1c2b", original: ".htaccess", fingerprint: "3f9a1c2b", ok: true},
+		{name: "app.js"},
+		{name: "app.3F9A1C2B.js"},
+		{name: "app.3f9a1c.js"},
+		{name: ".3f9a1c2b.js"},
+	} {
+		original, fingerprint, ok := splitFingerprint(tc.name)
+		if original != tc.original || fingerprint != tc.fingerprint || ok != tc.ok {
+			t.Errorf("%s: expected %q, %q, %t, got %q, %q, %t", tc.name, tc.original, tc.fingerprint, tc.ok, original, fingerprint, ok)
+		}
+		if tc.ok {
+			if name := fingerprintedName(tc.original, tc.fingerprint); name != tc.name {
+				t.Errorf("%s: fingerprinted name of %s is %q", tc.name, tc.original, name)
+			}
+		}
+	}
+}
@@ -1,3 +1,5 @@
 module github.com/prometheus/common/assets
 
 go 1.20
+
+require golang.org/x/net v0.20.0
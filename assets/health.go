@@ -0,0 +1,83 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand"
+	"net/http"
+)
+
+// HealthHandler returns a handler for liveness or readiness probes, checking
+// that the sample files, or a random one if none are given, can be read and
+// decompressed. It responds with 200 OK if so, and with 503 Service
+// Unavailable naming the failing file otherwise.
+func (compressed FileSystem) HealthHandler(sample ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		names := sample
+		if len(names) == 0 {
+			name, err := compressed.randomFile()
+			if err != nil {
+				http.Error(w, fmt.Sprintf("assets: %v", err), http.StatusServiceUnavailable)
+				return
+			}
+			names = []string{name}
+		}
+		for _, name := range names {
+			if err := compressed.checkFile(name); err != nil {
+				http.Error(w, fmt.Sprintf("%s: %v", name, err), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, "OK\n")
+	})
+}
+
+// checkFile reads all of the decompressed content of the named file.
+func (compressed FileSystem) checkFile(name string) error {
+	f, err := compressed.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(io.Discard, f)
+	return err
+}
+
+// randomFile returns the name of a random regular file.
+func (compressed FileSystem) randomFile() (string, error) {
+	var names []string
+	if compressed.index != nil && !compressed.index.lazy {
+		for name := range compressed.index.all {
+			names = append(names, name)
+		}
+	} else {
+		err := fs.WalkDir(compressed, ".", func(name string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				names = append(names, name)
+			}
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	if len(names) == 0 {
+		return "", fs.ErrNotExist
+	}
+	return names[rand.Intn(len(names))], nil
+}
@@ -0,0 +1,46 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHealthHandler(t *testing.T) {
+	for _, c := range []struct {
+		name           string
+		fsys           FileSystem
+		sample         []string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"samples", testFS, []string{"testdata/compressed", "testdata/uncompressed"}, http.StatusOK, "OK\n"},
+		{"random", New(templatesFS), nil, http.StatusOK, "OK\n"},
+		{"corrupt", brokenFS, []string{"testdata_broken/corrupt"}, http.StatusServiceUnavailable, "testdata_broken/corrupt: "},
+		{"missing", testFS, []string{"testdata/missing"}, http.StatusServiceUnavailable, "testdata/missing: "},
+	} {
+		rec := httptest.NewRecorder()
+		c.fsys.HealthHandler(c.sample...).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+		if rec.Code != c.expectedStatus {
+			t.Fatalf("%s: status is wrong, expected %d, got %d", c.name, c.expectedStatus, rec.Code)
+		}
+		if !strings.HasPrefix(rec.Body.String(), c.expectedBody) {
+			t.Fatalf("%s: body is wrong, expected prefix %q, got %q", c.name, c.expectedBody, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,1041 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// minCompressSize is the smallest dynamic response body, in bytes, that
+	// CompressionMiddleware compresses. Smaller bodies gain little and are
+	// sent as-is.
+	minCompressSize = 1024
+
+	// sniffLen is the number of bytes http.DetectContentType considers.
+	sniffLen = 512
+
+	// allowedMethods are the methods files can be requested with.
+	allowedMethods = "GET, HEAD, OPTIONS"
+)
+
+// EncoderFunc returns a writer compressing everything written to it into w.
+// Closing the returned writer must flush all pending data, but not close w.
+type EncoderFunc func(w io.Writer) io.WriteCloser
+
+var (
+	encodersMtx sync.RWMutex
+	// encoders holds the registered content codings in preference order.
+	encoders = []encoder{
+		{"gzip", func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }},
+	}
+)
+
+type encoder struct {
+	encoding string
+	fn       EncoderFunc
+}
+
+// RegisterEncoder registers fn as the compressor for the given content coding
+// (e.g. "br"), as used by CompressionMiddleware for dynamic responses. Codings
+// registered later are preferred over earlier ones when a client accepts
+// several of them with the same quality. Registering an already known coding
+// replaces its compressor.
+func RegisterEncoder(encoding string, fn EncoderFunc) {
+	encodersMtx.Lock()
+	defer encodersMtx.Unlock()
+
+	encoding = strings.ToLower(encoding)
+	for i, e := range encoders {
+		if e.encoding == encoding {
+			encoders = append(encoders[:i], encoders[i+1:]...)
+			break
+		}
+	}
+	encoders = append([]encoder{{encoding, fn}}, encoders...)
+}
+
+// lookupEncoders returns the registered codings in preference order, and a
+// lookup of their compressors.
+func lookupEncoders() ([]string, map[string]EncoderFunc) {
+	encodersMtx.RLock()
+	defer encodersMtx.RUnlock()
+
+	names := make([]string, 0, len(encoders))
+	fns := make(map[string]EncoderFunc, len(encoders))
+	for _, e := range encoders {
+		names = append(names, e.encoding)
+		fns[e.encoding] = e.fn
+	}
+	return names, fns
+}
+
+// NegotiateEncoding returns the content coding out of available that is
+// preferred by a client sending the given Accept-Encoding header value. Ties
+// between codings of the same quality are resolved by their order in
+// available. An empty string is returned if none of them is acceptable, in
+// which case the response should not be encoded.
+func NegotiateEncoding(acceptEncoding string, available []string) string {
+	accepted := parseAcceptEncoding(acceptEncoding)
+
+	var (
+		best  string
+		bestQ float64
+	)
+	for _, enc := range available {
+		q, ok := accepted[strings.ToLower(enc)]
+		if !ok {
+			q = accepted["*"]
+		}
+		if q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// parseAcceptEncoding returns the quality values of all codings listed in an
+// Accept-Encoding header value. Codings with a malformed quality value are
+// treated as not acceptable.
+func parseAcceptEncoding(header string) map[string]float64 {
+	accepted := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		enc := strings.ToLower(strings.TrimSpace(params[0]))
+		if enc == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.TrimSpace(k) != "q" {
+				continue
+			}
+			var err error
+			if q, err = strconv.ParseFloat(strings.TrimSpace(v), 64); err != nil {
+				q = 0
+			}
+		}
+		accepted[enc] = q
+	}
+	return accepted
+}
+
+// Handler returns a handler serving the regular files of fsys like
+// CompressionMiddleware, answering all other requests with 404 Not Found.
+// Stored compressed bytes are sent as-is to clients accepting their coding,
+// files are only decompressed for other clients.
+func Handler(fsys FileSystem) http.Handler {
+	return fsys.CompressionMiddleware(http.NotFoundHandler())
+}
+
+// ServeHTTP implements the http.Handler interface, see Handler.
+func (compressed FileSystem) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	Handler(compressed).ServeHTTP(w, r)
+}
+
+// CompressionMiddleware returns a handler serving the regular files of the
+// FileSystem directly, passing content stored as ".zst", ".br" or ".gz"
+// through to clients accepting zstd, br or gzip respectively. All other
+// requests are handed to next, with response bodies compressed using the
+// negotiated coding out of the registered encoders. Only gzip is registered by
+// default, brotli and other codings can be added with RegisterEncoder.
+// Responses that already carry a Content-Encoding, responses to HEAD and range
+// requests, partial responses or those with a status that doesn't allow a
+// body, bodies of media types that aren't known to compress well, and bodies
+// smaller than 1KiB are passed on unmodified.
+func (compressed FileSystem) CompressionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if compressed.opts != nil && compressed.opts.proxyFriendly {
+			w = &proxyFriendlyWriter{ResponseWriter: w}
+		}
+		if m := compressed.manifestEndpoint(); m != nil && r.URL.Path == m.path {
+			compressed.serveManifest(w, r, m)
+			return
+		}
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		if compressed.opts != nil && compressed.opts.normalizePaths {
+			name = strings.ReplaceAll(name, `\`, "/")
+		}
+		if target, ok := compressed.resolveAlias(name); ok {
+			if compressed.opts.redirectAliases {
+				// The reference is relative, as a prefix the request was
+				// mounted under has already been stripped from the path.
+				w.Header().Set("Location", relativeRef(name, target))
+				w.WriteHeader(http.StatusMovedPermanently)
+				return
+			}
+			name = target
+		}
+		name = compressed.resolveFingerprint(compressed.foldCase(name))
+		if !compressed.isFile(name) && compressed.opts != nil && compressed.opts.cleanURLs {
+			name = compressed.resolveCleanURL(name)
+		}
+		if compressed.isFile(name) {
+			compressed.serveAsset(w, r, name)
+			return
+		}
+
+		if compressed.skipsDecompression(name) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		names, fns := lookupEncoders()
+		w.Header().Add("Vary", "Accept-Encoding")
+		enc := NegotiateEncoding(r.Header.Get("Accept-Encoding"), names)
+		if enc == "" || r.Method == http.MethodHead || r.Header.Get("Range") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressingWriter{ResponseWriter: w, encoding: enc, newEncoder: fns[enc]}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// relativeRef returns the relative URL reference to the file target from that
+// of the file name, both given as slash separated paths.
+func relativeRef(name, target string) string {
+	from := strings.Split(name, "/")
+	from = from[:len(from)-1]
+	to := strings.Split(target, "/")
+	i := 0
+	for i < len(from) && i < len(to)-1 && from[i] == to[i] {
+		i++
+	}
+	ref := strings.Repeat("../", len(from)-i)
+	u := &url.URL{Path: strings.Join(to[i:], "/")}
+	if ref == "" && strings.Contains(to[i], ":") {
+		// Don't let the first segment be taken for a scheme.
+		ref = "./"
+	}
+	return ref + u.EscapedPath()
+}
+
+// resolveCleanURL returns the HTML file the extensionless name refers to, or
+// name itself if there is none.
+func (compressed FileSystem) resolveCleanURL(name string) string {
+	dir := strings.TrimSuffix(name, "/")
+	if path.Ext(dir) != "" {
+		return name
+	}
+	var candidates []string
+	if dir == "" {
+		candidates = []string{"index.html"}
+	} else {
+		candidates = []string{dir + ".html", dir + "/index.html"}
+	}
+	for _, candidate := range candidates {
+		if compressed.isFile(candidate) {
+			return candidate
+		}
+	}
+	return name
+}
+
+// storedEncoding is a content coding files may be stored with in the embed
+// FS, identified by its file name suffix.
+type storedEncoding struct {
+	encoding string
+	suffix   string
+}
+
+// storedEncodings lists the codings of stored files that are passed through to
+// clients accepting them, in order of preference.
+var storedEncodings = []storedEncoding{
+	{"zstd", zstdSuffix},
+	{"br", brSuffix},
+	{"gzip", gzipSuffix},
+}
+
+// gzipOnlyEncodings are the stored codings recognized by FileSystems created
+// with New.
+var gzipOnlyEncodings = []storedEncoding{{"gzip", gzipSuffix}}
+
+// codings returns the stored codings the FileSystem recognizes, in order of
+// preference.
+func (compressed FileSystem) codings() []storedEncoding {
+	if compressed.opts != nil && compressed.opts.gzipOnly {
+		return gzipOnlyEncodings
+	}
+	return storedEncodings
+}
+
+// suffixFor returns the file name suffix of the stored encoding, or an empty
+// string if the encoding is unknown.
+func suffixFor(encoding string) string {
+	for _, se := range storedEncodings {
+		if se.encoding == encoding {
+			return se.suffix
+		}
+	}
+	return ""
+}
+
+// storedVariants returns the compressed codings the file name is stored with,
+// in order of preference.
+func (compressed FileSystem) storedVariants(name string) []string {
+	encodings := compressed.index.lookup(name)
+	if n := len(encodings); n > 0 && encodings[n-1] == identityEncoding {
+		encodings = encodings[:n-1]
+	}
+	return encodings
+}
+
+// selectVariant returns the stored name of the copy of name in the given
+// coding to send, as chosen by the selector set with WithVariantSelector.
+func (compressed FileSystem) selectVariant(r *http.Request, name, encoding string) string {
+	stored := name + suffixFor(encoding)
+	if compressed.opts == nil || !compressed.opts.selectVariants {
+		return stored
+	}
+	candidates, _ := fs.Glob(compressed.embed, escapeGlob(name)+".*"+suffixFor(encoding))
+	if compressed.isStored(stored) {
+		candidates = append([]string{stored}, candidates...)
+	}
+	if len(candidates) == 0 {
+		return stored
+	}
+	if compressed.opts.variantSelector == nil {
+		return compressed.smallestFile(candidates)
+	}
+	selected := compressed.opts.variantSelector(r, candidates)
+	for _, c := range candidates {
+		if c == selected {
+			return selected
+		}
+	}
+	return candidates[0]
+}
+
+// smallestFile returns the smallest of the stored files names.
+func (compressed FileSystem) smallestFile(names []string) string {
+	var (
+		smallest string
+		minSize  int64
+	)
+	for _, name := range names {
+		stat, err := fs.Stat(compressed.embed, name)
+		if err != nil {
+			continue
+		}
+		if smallest == "" || stat.Size() < minSize {
+			smallest, minSize = name, stat.Size()
+		}
+	}
+	return smallest
+}
+
+// smallestVariant returns the coding out of variants whose stored copy of name
+// is the smallest, among those accepted according to acceptEncoding.
+func (compressed FileSystem) smallestVariant(name, acceptEncoding string, variants []string) string {
+	var (
+		smallest string
+		minSize  int64
+	)
+	for _, enc := range variants {
+		if NegotiateEncoding(acceptEncoding, []string{enc}) == "" {
+			continue
+		}
+		stat, err := fs.Stat(compressed.embed, name+suffixFor(enc))
+		if err != nil {
+			continue
+		}
+		if smallest == "" || stat.Size() < minSize {
+			smallest, minSize = enc, stat.Size()
+		}
+	}
+	return smallest
+}
+
+// isStored reports whether name is a regular file in the embed FS.
+func (compressed FileSystem) isStored(name string) bool {
+	for _, se := range compressed.codings() {
+		if strings.HasSuffix(name, se.suffix) {
+			return compressed.hasEncoding(strings.TrimSuffix(name, se.suffix), se.encoding)
+		}
+	}
+	return compressed.hasEncoding(name, identityEncoding)
+}
+
+// isFile reports whether name resolves to a regular file, compressed or not.
+func (compressed FileSystem) isFile(name string) bool {
+	if name == "" || !fs.ValidPath(name) {
+		return false
+	}
+	return compressed.isStored(name) || len(compressed.storedVariants(name)) > 0
+}
+
+// checkMethod reports whether the request for a file is a GET or HEAD request,
+// answering it otherwise: OPTIONS requests with the allowed methods, all
+// others with 405 Method Not Allowed.
+func checkMethod(w http.ResponseWriter, r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	case http.MethodOptions:
+		w.Header().Set("Allow", allowedMethods)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", allowedMethods)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+	return false
+}
+
+// serveAsset writes the file name to w. Stored compressed bytes are sent as-is
+// when the client accepts their coding, otherwise the decompressed content is
+// sent. OPTIONS requests are answered with the allowed methods, other methods
+// than GET and HEAD are rejected.
+func (compressed FileSystem) serveAsset(w http.ResponseWriter, r *http.Request, name string) {
+	if !checkMethod(w, r) {
+		return
+	}
+	compressed.addSecurityHeaders(w.Header())
+	w.Header().Add("Vary", "Accept-Encoding")
+	if err := compressed.addPreloadLinks(w.Header(), name); err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if compressed.isImmutable(name) {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		if r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != "" {
+			// Whatever version the client has, it is the current one.
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	variants := compressed.storedVariants(name)
+	enc := NegotiateEncoding(r.Header.Get("Accept-Encoding"), variants)
+	if enc != "" && compressed.opts != nil && compressed.opts.preferSmallest {
+		enc = compressed.smallestVariant(name, r.Header.Get("Accept-Encoding"), variants)
+	}
+	if enc != "" {
+		if compressed.servePassthrough(w, r, name, enc, false) {
+			return
+		}
+	} else if len(variants) > 0 && compressed.skipsDecompression(name) && !compressed.hasEncoding(name, identityEncoding) {
+		// Send the stored bytes as they are rather than decoding them.
+		if compressed.servePassthrough(w, r, name, variants[0], true) {
+			return
+		}
+	}
+
+	f, err := compressed.Open(name)
+	if err != nil {
+		if (errors.Is(err, fs.ErrNotExist) || errors.Is(err, errNoDecoder)) && len(variants) > 0 {
+			// The file is only stored in codings the client doesn't accept.
+			http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	if sf, ok := f.(*File); ok && sf.reader != nil {
+		compressed.serveStream(w, r, name, sf)
+		return
+	}
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		content, err := io.ReadAll(f)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		rs = bytes.NewReader(content)
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	etag, err := compressed.etagOf(name, rs)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	compressed.runResponseHook(w, r, name, stat)
+	http.ServeContent(w, r, name, time.Time{}, rs)
+}
+
+// runResponseHook calls the hook set with WithResponseHook, if any, keeping
+// the Content-Length and Content-Encoding headers as they were.
+func (compressed FileSystem) runResponseHook(w http.ResponseWriter, r *http.Request, name string, info fs.FileInfo) {
+	if compressed.opts == nil || compressed.opts.responseHook == nil {
+		return
+	}
+	h := w.Header()
+	protected := map[string][]string{}
+	for _, k := range []string{"Content-Length", "Content-Encoding"} {
+		protected[k] = h.Values(k)
+	}
+	compressed.opts.responseHook(w, r, name, info)
+	for k, v := range protected {
+		if len(v) == 0 {
+			h.Del(k)
+		} else {
+			h[k] = v
+		}
+	}
+}
+
+// addPreloadLinks adds the Link headers for the dependencies of name set with
+// WithPreloadLinks to h.
+func (compressed FileSystem) addPreloadLinks(h http.Header, name string) error {
+	if compressed.opts == nil {
+		return nil
+	}
+	for _, dep := range compressed.opts.preloadLinks[name] {
+		ctype, err := compressed.contentType(dep)
+		if err != nil {
+			return err
+		}
+		link := "</" + dep + ">; rel=preload; as=" + preloadDestination(ctype)
+		if strings.HasPrefix(ctype, "font/") {
+			// Fonts are always fetched in CORS mode, and the preload is
+			// only used if it was as well.
+			link += "; crossorigin"
+		}
+		h.Add("Link", link)
+	}
+	return nil
+}
+
+// preloadDestination returns the "as" attribute of a preload link for content
+// of the given media type.
+func preloadDestination(ctype string) string {
+	mediaType, _, _ := mime.ParseMediaType(ctype)
+	switch {
+	case mediaType == "text/javascript", mediaType == "application/javascript":
+		return "script"
+	case mediaType == "text/css":
+		return "style"
+	case strings.HasPrefix(mediaType, "font/"):
+		return "font"
+	case strings.HasPrefix(mediaType, "image/"):
+		return "image"
+	default:
+		return "fetch"
+	}
+}
+
+// serveStream writes the streaming file f to w, without holding its entire
+// content in memory. As f can't seek, range requests aren't supported, and
+// conditional requests are answered using a weak ETag.
+func (compressed FileSystem) serveStream(w http.ResponseWriter, r *http.Request, name string, f *File) {
+	ctype, err := compressed.contentType(name)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	encoding, err := compressed.decodeEncoding(name)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	etag, err := compressed.weakETag(name+suffixFor(encoding), stat.Size())
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	if matchesWeak(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", ctype)
+	if stat.Size() >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(stat.Size(), 10))
+	}
+	compressed.runResponseHook(w, r, name, stat)
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		io.Copy(w, f)
+	}
+}
+
+// servePassthrough writes the stored bytes of name in the given coding to w.
+// If identity is set, they are sent as a file of that coding, e.g.
+// application/gzip, without a Content-Encoding, for clients not accepting it.
+// It returns false if nothing was written, as the variant couldn't be opened.
+func (compressed FileSystem) servePassthrough(w http.ResponseWriter, r *http.Request, name, encoding string, identity bool) bool {
+	stored := compressed.selectVariant(r, name, encoding)
+	f, err := compressed.embed.Open(stored)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		return false
+	}
+
+	ctype, err := compressed.contentType(name)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return true
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return true
+	}
+	etag, err := compressed.etagOf(stored, rs)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return true
+	}
+	w.Header().Set("ETag", etag)
+	if identity {
+		w.Header().Set("Content-Type", encodedMediaType(encoding))
+		compressed.runResponseHook(w, r, name, stat)
+		http.ServeContent(w, r, name, time.Time{}, rs)
+		return true
+	}
+	w.Header().Set("Content-Type", ctype)
+	w.Header().Set("Content-Encoding", encoding)
+	compressed.runResponseHook(w, r, name, stat)
+	if encoding == "gzip" && r.Header.Get("Range") != "" && compressed.serveMemberRange(w, r, name, rs, stat.Size(), etag) {
+		return true
+	}
+	http.ServeContent(w, r, name, time.Time{}, rs)
+	return true
+}
+
+// encodedMediaType returns the media type of content compressed with the given
+// coding, when sent without a Content-Encoding.
+func encodedMediaType(encoding string) string {
+	switch encoding {
+	case "gzip":
+		return "application/gzip"
+	case "zstd":
+		return "application/zstd"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// ETag returns the strong ETag sent for the decompressed content of the named
+// file, the quoted hex encoded hash of the content. SHA-256 is used unless
+// configured otherwise with WithHasher, in which case the hash is prefixed with
+// the name of the algorithm, e.g. "xxhash-". It is computed on first use and
+// memoized.
+func (compressed FileSystem) ETag(path string) (string, error) {
+	name, err := compressed.resolvePath("open", path)
+	if err != nil {
+		return "", err
+	}
+	if etag, ok := compressed.knownETag(name); ok {
+		return etag, nil
+	}
+	content, err := fs.ReadFile(compressed, name)
+	if err != nil {
+		return "", err
+	}
+	return compressed.etag(name, content), nil
+}
+
+// etag returns a strong ETag for the content stored under key. As the
+// content of a key never changes, the ETag is memoized. Each representation
+// of a file is stored under a different key, giving them distinct ETags, as
+// required for byte ranges of different representations not to be mixed.
+func (compressed FileSystem) etag(key string, content []byte) string {
+	if compressed.etags != nil {
+		if etag, ok := compressed.etags.Load(key); ok {
+			return etag.(string)
+		}
+	}
+	h := compressed.newHash()
+	h.Write(content)
+	etag := `"` + compressed.etagSum(h) + `"`
+	if compressed.etags != nil {
+		compressed.etags.Store(key, etag)
+	}
+	return etag
+}
+
+// etagSum returns the hex encoded sum of h for use in ETags, prefixed with the
+// name of the hasher if configured with WithHasher.
+func (compressed FileSystem) etagSum(h hash.Hash) string {
+	sum := hex.EncodeToString(h.Sum(nil))
+	if compressed.opts != nil && compressed.opts.hasherName != "" {
+		return compressed.opts.hasherName + "-" + sum
+	}
+	return sum
+}
+
+// etagOf returns the strong ETag of the content read from rs, memoized under
+// key like with etag. The content is only read if the ETag isn't known yet,
+// after which rs is rewound, so that it doesn't have to be buffered.
+func (compressed FileSystem) etagOf(key string, rs io.ReadSeeker) (string, error) {
+	if etag, ok := compressed.knownETag(key); ok {
+		return etag, nil
+	}
+	h := compressed.newHash()
+	if _, err := io.Copy(h, rs); err != nil {
+		return "", err
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"` + compressed.etagSum(h) + `"`
+	if compressed.etags != nil {
+		compressed.etags.Store(key, etag)
+	}
+	return etag, nil
+}
+
+// weakETag returns a weak ETag for the stored file, derived from the hash of
+// its bytes and the given size of its decompressed content, which is left out
+// if it is unknown, i.e. negative. Unlike etag, this doesn't need the
+// decompressed content. As the same content can be
+// compressed differently, it is only a weak validator, which must not be used
+// for If-Range.
+func (compressed FileSystem) weakETag(stored string, size int64) (string, error) {
+	key := stored + "\x00weak"
+	if etag, ok := compressed.knownETag(key); ok {
+		return etag, nil
+	}
+	f, err := compressed.embed.Open(stored)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := compressed.newHash()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	etag := `W/"` + compressed.etagSum(h)
+	if size >= 0 {
+		etag += "-" + strconv.FormatInt(size, 16)
+	}
+	etag += `"`
+	if compressed.etags != nil {
+		compressed.etags.Store(key, etag)
+	}
+	return etag, nil
+}
+
+// matchesWeak reports whether the If-None-Match header value matches etag,
+// using the weak comparison.
+func matchesWeak(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// contentType returns the media type of the file name, based on its extension
+// or, failing that, on sniffing its decompressed content. Files that can't be
+// decompressed are reported as application/octet-stream.
+func (compressed FileSystem) contentType(name string) (string, error) {
+	if ctype := typeByExtension(name); ctype != "" {
+		return ctype, nil
+	}
+	f, err := compressed.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "application/octet-stream", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// typeByExtension returns the media type of the file name based on its
+// extension, or an empty string if it is unknown.
+func typeByExtension(name string) string {
+	return mime.TypeByExtension(path.Ext(name))
+}
+
+// sniffContentType returns the media type of the content of f, as detected by
+// http.DetectContentType, and a file reading all of that content. This is f
+// itself, unless reading the sniffed bytes consumed them.
+func sniffContentType(f fs.File) (string, fs.File, error) {
+	buf := make([]byte, sniffLen)
+	if ra, ok := f.(io.ReaderAt); ok {
+		if n, err := ra.ReadAt(buf, 0); err == nil || err == io.EOF {
+			return http.DetectContentType(buf[:n]), f, nil
+		}
+		// Streaming files don't support ReadAt, fall back to reading them.
+	}
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", nil, err
+	}
+	return http.DetectContentType(buf[:n]), &sniffedFile{File: f, r: io.MultiReader(bytes.NewReader(buf[:n]), f)}, nil
+}
+
+// sniffedFile is a file whose first bytes were read for sniffing its content
+// type, and are read again from r.
+type sniffedFile struct {
+	fs.File
+	r io.Reader
+}
+
+// Read implements the fs.File interface.
+func (f *sniffedFile) Read(p []byte) (int, error) {
+	return f.r.Read(p)
+}
+
+// compressingWriter compresses the response body once it is known to be at
+// least minCompressSize bytes long. Until then, the body is buffered. Bodies
+// which aren't compressible are passed through as soon as that's known.
+type compressingWriter struct {
+	http.ResponseWriter
+	encoding   string
+	newEncoder EncoderFunc
+
+	status      int
+	buf         []byte
+	encoder     io.WriteCloser
+	passthrough bool
+	wroteHeader bool
+}
+
+// WriteHeader implements http.ResponseWriter. The status is held back until
+// it is known whether the body will be compressed.
+func (cw *compressingWriter) WriteHeader(status int) {
+	if cw.status == 0 {
+		cw.status = status
+	}
+}
+
+// Write implements http.ResponseWriter.
+func (cw *compressingWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	switch {
+	case cw.encoder != nil:
+		return cw.encoder.Write(p)
+	case cw.passthrough:
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= minCompressSize || !cw.compressible() {
+		if err := cw.commit(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush implements http.Flusher. A flush commits to compressing the body if
+// it can be, as streamed responses can't be held back.
+func (cw *compressingWriter) Flush() {
+	if cw.encoder == nil && !cw.passthrough {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		if err := cw.commit(); err != nil {
+			return
+		}
+	}
+	if f, ok := cw.encoder.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close writes out any buffered body and finishes the compressed stream.
+func (cw *compressingWriter) Close() error {
+	if cw.encoder != nil {
+		return cw.encoder.Close()
+	}
+	if cw.passthrough || cw.status == 0 {
+		return nil
+	}
+	cw.writeHeader()
+	_, err := cw.ResponseWriter.Write(cw.buf)
+	return err
+}
+
+// compressible reports whether the body may be compressed, judging by the
+// status and headers of the response. Bodies without a Content-Type yet are
+// judged once it is sniffed.
+func (cw *compressingWriter) compressible() bool {
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" || !bodyAllowed(cw.status) {
+		return false
+	}
+	// Ranges refer to the identity encoded body.
+	if cw.status == http.StatusPartialContent || h.Get("Content-Range") != "" {
+		return false
+	}
+	ctype := h.Get("Content-Type")
+	return ctype == "" || compressibleType(ctype)
+}
+
+// commit writes the header, and the buffered body either compressed or as-is,
+// depending on whether it is compressible.
+func (cw *compressingWriter) commit() error {
+	h := cw.Header()
+	if h.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		// Once encoded, net/http can't sniff the content anymore.
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+	buf := cw.buf
+	cw.buf = nil
+	if !cw.compressible() {
+		cw.passthrough = true
+		cw.writeHeader()
+		_, err := cw.ResponseWriter.Write(buf)
+		return err
+	}
+
+	h.Set("Content-Encoding", cw.encoding)
+	h.Del("Content-Length")
+	cw.writeHeader()
+	cw.encoder = cw.newEncoder(cw.ResponseWriter)
+	_, err := cw.encoder.Write(buf)
+	return err
+}
+
+func (cw *compressingWriter) writeHeader() {
+	if !cw.wroteHeader {
+		cw.wroteHeader = true
+		cw.ResponseWriter.WriteHeader(cw.status)
+	}
+}
+
+// bodyAllowed reports whether a response with the given status may have a
+// body, as per RFC 9110.
+func bodyAllowed(status int) bool {
+	return status >= http.StatusOK && status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// compressibleType reports whether content of the media type ctype is known
+// to compress well, i.e. is text based.
+func compressibleType(ctype string) bool {
+	mediaType, _, err := mime.ParseMediaType(ctype)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/x-ndjson", "application/javascript",
+		"application/xml", "application/wasm", "application/openmetrics-text":
+		return true
+	}
+	return false
+}
+
+// proxyFriendlyWriter normalizes the response headers before they are sent, as
+// configured with WithProxyFriendlyHeaders.
+type proxyFriendlyWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (pw *proxyFriendlyWriter) WriteHeader(status int) {
+	if !pw.wroteHeader {
+		pw.wroteHeader = true
+		normalizeHeaders(pw.Header())
+	}
+	pw.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter.
+func (pw *proxyFriendlyWriter) Write(p []byte) (int, error) {
+	if !pw.wroteHeader {
+		pw.WriteHeader(http.StatusOK)
+	}
+	return pw.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher.
+func (pw *proxyFriendlyWriter) Flush() {
+	if !pw.wroteHeader {
+		pw.WriteHeader(http.StatusOK)
+	}
+	if f, ok := pw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// normalizeHeaders collapses the Content-Encoding of h into a single value
+// holding each coding once, sets Date if missing and removes
+// Transfer-Encoding.
+func normalizeHeaders(h http.Header) {
+	if values := h.Values("Content-Encoding"); len(values) > 0 {
+		var codings []string
+		seen := map[string]bool{}
+		for _, value := range values {
+			for _, coding := range strings.Split(value, ",") {
+				coding = strings.ToLower(strings.TrimSpace(coding))
+				if coding != "" && !seen[coding] {
+					seen[coding] = true
+					codings = append(codings, coding)
+				}
+			}
+		}
+		if len(codings) > 0 {
+			h.Set("Content-Encoding", strings.Join(codings, ", "))
+		} else {
+			h.Del("Content-Encoding")
+		}
+	}
+	if h.Get("Date") == "" {
+		h.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+	h.Del("Transfer-Encoding")
+}
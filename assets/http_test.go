@@ -0,0 +1,705 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	cases := []struct {
+		accept    string
+		available []string
+		expected  string
+	}{
+		{accept: "", available: []string{"gzip"}, expected: ""},
+		{accept: "gzip", available: []string{"gzip"}, expected: "gzip"},
+		{accept: "GZIP", available: []string{"gzip"}, expected: "gzip"},
+		{accept: "deflate", available: []string{"gzip"}, expected: ""},
+		{accept: "gzip;q=0", available: []string{"gzip"}, expected: ""},
+		{accept: "*", available: []string{"br", "gzip"}, expected: "br"},
+		{accept: "*;q=0, gzip", available: []string{"br", "gzip"}, expected: "gzip"},
+		{accept: "gzip, br", available: []string{"br", "gzip"}, expected: "br"},
+		{accept: "gzip;q=1.0, br;q=0.5", available: []string{"br", "gzip"}, expected: "gzip"},
+		{accept: "gzip;q=bad, br;q=0.1", available: []string{"gzip", "br"}, expected: "br"},
+	}
+
+	for _, c := range cases {
+		if got := NegotiateEncoding(c.accept, c.available); got != c.expected {
+			t.Errorf("NegotiateEncoding(%q, %v): expected %q, got %q", c.accept, c.available, c.expected, got)
+		}
+	}
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	large := strings.Repeat("{\"foo\":\"bar\"}", 100)
+	handler := testFS.CompressionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/large":
+			io.WriteString(w, large)
+		case "/small":
+			io.WriteString(w, "{}")
+		case "/encoded":
+			w.Header().Set("Content-Encoding", "custom")
+			io.WriteString(w, large)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	cases := []struct {
+		name             string
+		path             string
+		accept           string
+		expectedEncoding string
+		expectedBody     string
+	}{
+		{
+			name:             "large dynamic body",
+			path:             "/large",
+			accept:           "gzip",
+			expectedEncoding: "gzip",
+			expectedBody:     large,
+		},
+		{
+			name:         "large dynamic body, no gzip",
+			path:         "/large",
+			expectedBody: large,
+		},
+		{
+			name:         "small dynamic body",
+			path:         "/small",
+			accept:       "gzip",
+			expectedBody: "{}",
+		},
+		{
+			name:             "already encoded body",
+			path:             "/encoded",
+			accept:           "gzip",
+			expectedEncoding: "custom",
+			expectedBody:     large,
+		},
+		{
+			name:             "compressed asset",
+			path:             "/testdata/compressed",
+			accept:           "gzip",
+			expectedEncoding: "gzip",
+			expectedBody:     "foo\n",
+		},
+		{
+			name:         "compressed asset, no gzip",
+			path:         "/testdata/compressed",
+			expectedBody: "foo\n",
+		},
+		{
+			name:         "uncompressed asset",
+			path:         "/testdata/uncompressed",
+			accept:       "gzip",
+			expectedBody: "foo\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			if c.accept != "" {
+				req.Header.Set("Accept-Encoding", c.accept)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status is wrong, expected %d, got %d", http.StatusOK, rec.Code)
+			}
+			encoding := rec.Header().Get("Content-Encoding")
+			if encoding != c.expectedEncoding {
+				t.Fatalf("encoding is wrong, expected %q, got %q", c.expectedEncoding, encoding)
+			}
+
+			body := rec.Body.Bytes()
+			if encoding == "gzip" {
+				gr, err := gzip.NewReader(bytes.NewReader(body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if body, err = io.ReadAll(gr); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if string(body) != c.expectedBody {
+				t.Fatalf("body is wrong, expected %q, got %q", c.expectedBody, string(body))
+			}
+		})
+	}
+}
+
+func TestCompressionMiddlewareSkipsCompression(t *testing.T) {
+	large := strings.Repeat("{\"foo\":\"bar\"}", 100)
+	handler := testFS.CompressionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+		case "/not-modified":
+			w.WriteHeader(http.StatusNotModified)
+			return
+		case "/no-content":
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case "/flushed":
+			w.Header().Set("Content-Encoding", "custom")
+			w.(http.Flusher).Flush()
+		case "/partial":
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(large)-1, 2*len(large)))
+			w.WriteHeader(http.StatusPartialContent)
+		case "/served":
+			http.ServeContent(w, r, "large.json", time.Time{}, strings.NewReader(large))
+			return
+		}
+		io.WriteString(w, large)
+	}))
+
+	cases := []struct {
+		name             string
+		method           string
+		path             string
+		rangeHeader      string
+		expectedStatus   int
+		expectedEncoding string
+	}{
+		{name: "incompressible media type", method: http.MethodGet, path: "/image", expectedStatus: http.StatusOK},
+		{name: "HEAD request", method: http.MethodHead, path: "/large", expectedStatus: http.StatusOK},
+		{name: "304 response", method: http.MethodGet, path: "/not-modified", expectedStatus: http.StatusNotModified},
+		{name: "204 response", method: http.MethodGet, path: "/no-content", expectedStatus: http.StatusNoContent},
+		{name: "flushed encoded body", method: http.MethodGet, path: "/flushed", expectedStatus: http.StatusOK, expectedEncoding: "custom"},
+		{name: "206 response", method: http.MethodGet, path: "/partial", expectedStatus: http.StatusPartialContent},
+		{name: "range request", method: http.MethodGet, path: "/served", rangeHeader: "bytes=10-1209", expectedStatus: http.StatusPartialContent},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			if c.rangeHeader != "" {
+				req.Header.Set("Range", c.rangeHeader)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != c.expectedStatus {
+				t.Fatalf("status is wrong, expected %d, got %d", c.expectedStatus, rec.Code)
+			}
+			if encoding := rec.Header().Get("Content-Encoding"); encoding != c.expectedEncoding {
+				t.Fatalf("encoding is wrong, expected %q, got %q", c.expectedEncoding, encoding)
+			}
+		})
+	}
+
+	// The content type of compressed bodies is sniffed from the uncompressed
+	// content.
+	req := httptest.NewRequest(http.MethodGet, "/large", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if ctype := rec.Header().Get("Content-Type"); ctype != "text/plain; charset=utf-8" {
+		t.Fatalf("content type is wrong, got %q", ctype)
+	}
+	if encoding := rec.Header().Get("Content-Encoding"); encoding != "gzip" {
+		t.Fatalf("encoding is wrong, expected gzip, got %q", encoding)
+	}
+}
+
+func TestCompressionMiddlewarePassthrough(t *testing.T) {
+	stored, err := EmbedFS.ReadFile("testdata/compressed.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := testFS.CompressionMiddleware(http.NotFoundHandler())
+	req := httptest.NewRequest(http.MethodGet, "/testdata/compressed", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if !bytes.Equal(rec.Body.Bytes(), stored) {
+		t.Fatal("expected the stored gzip bytes to be served as-is")
+	}
+	if ctype := rec.Header().Get("Content-Type"); ctype != "text/plain; charset=utf-8" {
+		t.Fatalf("content type is wrong, got %q", ctype)
+	}
+}
+
+func TestRegisterEncoder(t *testing.T) {
+	RegisterEncoder("x-test", func(w io.Writer) io.WriteCloser {
+		return nopWriteCloser{w}
+	})
+	defer func() {
+		encodersMtx.Lock()
+		encoders = encoders[1:]
+		encodersMtx.Unlock()
+	}()
+
+	large := strings.Repeat("a", minCompressSize)
+	handler := testFS.CompressionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, large)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/dynamic", nil)
+	req.Header.Set("Accept-Encoding", "gzip, x-test")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if encoding := rec.Header().Get("Content-Encoding"); encoding != "x-test" {
+		t.Fatalf("expected the registered encoder to be preferred, got %q", encoding)
+	}
+	if rec.Body.String() != large {
+		t.Fatal("body is wrong")
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestCompressionMiddlewareStoredVariants(t *testing.T) {
+	gzipped, err := EmbedFS.ReadFile("testdata/variants/data.txt.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zstded, err := EmbedFS.ReadFile("testdata/variants/data.txt.zst")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name             string
+		path             string
+		accept           string
+		expectedStatus   int
+		expectedEncoding string
+		expectedBody     []byte
+	}{
+		{
+			name:             "zstd accepted",
+			path:             "/testdata/variants/data.txt",
+			accept:           "zstd",
+			expectedStatus:   http.StatusOK,
+			expectedEncoding: "zstd",
+			expectedBody:     zstded,
+		},
+		{
+			name:             "zstd preferred",
+			path:             "/testdata/variants/data.txt",
+			accept:           "gzip, deflate, br, zstd",
+			expectedStatus:   http.StatusOK,
+			expectedEncoding: "zstd",
+			expectedBody:     zstded,
+		},
+		{
+			name:             "gzip preferred by quality",
+			path:             "/testdata/variants/data.txt",
+			accept:           "zstd;q=0.5, gzip",
+			expectedStatus:   http.StatusOK,
+			expectedEncoding: "gzip",
+			expectedBody:     gzipped,
+		},
+		{
+			name:           "no encoding accepted",
+			path:           "/testdata/variants/data.txt",
+			expectedStatus: http.StatusOK,
+			expectedBody:   []byte("variant content\n"),
+		},
+		{
+			name:             "zstd only, accepted",
+			path:             "/testdata/variants/only.txt",
+			accept:           "zstd",
+			expectedStatus:   http.StatusOK,
+			expectedEncoding: "zstd",
+			expectedBody:     zstded,
+		},
+		{
+			name:           "zstd only, not accepted",
+			path:           "/testdata/variants/only.txt",
+			accept:         "gzip",
+			expectedStatus: http.StatusNotAcceptable,
+		},
+	}
+
+	handler := testFS.CompressionMiddleware(http.NotFoundHandler())
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			if c.accept != "" {
+				req.Header.Set("Accept-Encoding", c.accept)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != c.expectedStatus {
+				t.Fatalf("status is wrong, expected %d, got %d", c.expectedStatus, rec.Code)
+			}
+			if c.expectedStatus != http.StatusOK {
+				return
+			}
+			if encoding := rec.Header().Get("Content-Encoding"); encoding != c.expectedEncoding {
+				t.Fatalf("encoding is wrong, expected %q, got %q", c.expectedEncoding, encoding)
+			}
+			if ctype := rec.Header().Get("Content-Type"); ctype != "text/plain; charset=utf-8" {
+				t.Fatalf("content type is wrong, got %q", ctype)
+			}
+			if !bytes.Equal(rec.Body.Bytes(), c.expectedBody) {
+				t.Fatalf("body is wrong, expected %q, got %q", c.expectedBody, rec.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestIfRange(t *testing.T) {
+	handler := testFS.CompressionMiddleware(http.NotFoundHandler())
+	get := func(accept string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/testdata/variants/data.txt", nil)
+		req.Header = header
+		if accept != "" {
+			req.Header.Set("Accept-Encoding", accept)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, accept := range []string{"", "gzip"} {
+		t.Run("accept "+accept, func(t *testing.T) {
+			full := get(accept, http.Header{})
+			etag := full.Header().Get("ETag")
+			if etag == "" {
+				t.Fatal("expected an ETag")
+			}
+
+			cases := []struct {
+				name           string
+				ifRange        string
+				expectedStatus int
+				expectedBody   []byte
+			}{
+				{
+					name:           "matching ETag",
+					ifRange:        etag,
+					expectedStatus: http.StatusPartialContent,
+					expectedBody:   full.Body.Bytes()[2:5],
+				},
+				{
+					name:           "mismatching ETag",
+					ifRange:        `"stale"`,
+					expectedStatus: http.StatusOK,
+					expectedBody:   full.Body.Bytes(),
+				},
+				{
+					name:           "weak ETag",
+					ifRange:        "W/" + etag,
+					expectedStatus: http.StatusOK,
+					expectedBody:   full.Body.Bytes(),
+				},
+				{
+					// There is no Last-Modified to validate against.
+					name:           "date",
+					ifRange:        "Mon, 02 Jan 2006 15:04:05 GMT",
+					expectedStatus: http.StatusOK,
+					expectedBody:   full.Body.Bytes(),
+				},
+			}
+			for _, c := range cases {
+				t.Run(c.name, func(t *testing.T) {
+					rec := get(accept, http.Header{"Range": {"bytes=2-4"}, "If-Range": {c.ifRange}})
+					if rec.Code != c.expectedStatus {
+						t.Fatalf("status is wrong, expected %d, got %d", c.expectedStatus, rec.Code)
+					}
+					if !bytes.Equal(rec.Body.Bytes(), c.expectedBody) {
+						t.Fatalf("body is wrong, expected %q, got %q", c.expectedBody, rec.Body.Bytes())
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestETagPerRepresentation(t *testing.T) {
+	handler := testFS.CompressionMiddleware(http.NotFoundHandler())
+	etags := map[string]bool{}
+	for _, accept := range []string{"", "gzip", "zstd"} {
+		req := httptest.NewRequest(http.MethodGet, "/testdata/variants/data.txt", nil)
+		req.Header.Set("Accept-Encoding", accept)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		etags[rec.Header().Get("ETag")] = true
+	}
+	if len(etags) != 3 {
+		t.Fatalf("expected distinct ETags per representation, got %v", etags)
+	}
+}
+
+// countingWriter is a http.ResponseWriter discarding the body.
+type countingWriter struct {
+	header  http.Header
+	status  int
+	written int64
+}
+
+func (w *countingWriter) Header() http.Header { return w.header }
+
+func (w *countingWriter) WriteHeader(status int) { w.status = status }
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+	return len(p), nil
+}
+
+func TestStreaming(t *testing.T) {
+	const size = 16 << 20
+	fsys, err := NewWithOptions(EmbedFS, WithStreaming())
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := fsys.CompressionMiddleware(http.NotFoundHandler())
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	w := &countingWriter{header: http.Header{}}
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/testdata/large.bin", nil))
+	runtime.ReadMemStats(&after)
+
+	if w.status != http.StatusOK {
+		t.Fatalf("status is wrong, expected %d, got %d", http.StatusOK, w.status)
+	}
+	if w.written != size {
+		t.Fatalf("expected %d bytes to be written, got %d", size, w.written)
+	}
+	if length := w.header.Get("Content-Length"); length != strconv.Itoa(size) {
+		t.Fatalf("content length is wrong, got %q", length)
+	}
+	// Allow for some overhead, but far less than the decompressed size.
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > size/8 {
+		t.Fatalf("expected memory use to be bounded, %d bytes were allocated", allocated)
+	}
+}
+
+func TestStreamingOpen(t *testing.T) {
+	fsys, err := NewWithOptions(EmbedFS, WithStreaming())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := fsys.Open("testdata/compressed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Size() != 4 {
+		t.Fatalf("size is wrong, expected 4, got %d", stat.Size())
+	}
+	content, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "foo\n" {
+		t.Fatalf("content is wrong, got %q", string(content))
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Read(make([]byte, 1)); !errors.Is(err, fs.ErrClosed) {
+		t.Fatalf("expected fs.ErrClosed, got %v", err)
+	}
+}
+
+func TestMethods(t *testing.T) {
+	handler := testFS.CompressionMiddleware(http.NotFoundHandler())
+
+	for _, c := range []struct {
+		method         string
+		path           string
+		expectedStatus int
+		expectedAllow  string
+		expectedBody   string
+	}{
+		{http.MethodGet, "/testdata/compressed", http.StatusOK, "", "foo\n"},
+		{http.MethodHead, "/testdata/compressed", http.StatusOK, "", ""},
+		{http.MethodOptions, "/testdata/compressed", http.StatusNoContent, "GET, HEAD, OPTIONS", ""},
+		{http.MethodPost, "/testdata/compressed", http.StatusMethodNotAllowed, "GET, HEAD, OPTIONS", ""},
+		{http.MethodGet, "/testdata/missing", http.StatusNotFound, "", ""},
+		{http.MethodHead, "/testdata/missing", http.StatusNotFound, "", ""},
+		{http.MethodOptions, "/testdata/missing", http.StatusNotFound, "", ""},
+		{http.MethodPost, "/testdata/missing", http.StatusNotFound, "", ""},
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(c.method, c.path, nil))
+
+		if rec.Code != c.expectedStatus {
+			t.Fatalf("%s %s: status is wrong, expected %d, got %d", c.method, c.path, c.expectedStatus, rec.Code)
+		}
+		if allow := rec.Header().Get("Allow"); allow != c.expectedAllow {
+			t.Fatalf("%s %s: Allow is wrong, expected %q, got %q", c.method, c.path, c.expectedAllow, allow)
+		}
+		if c.expectedBody != "" && rec.Body.String() != c.expectedBody {
+			t.Fatalf("%s %s: body is wrong, expected %q, got %q", c.method, c.path, c.expectedBody, rec.Body.String())
+		}
+		if c.method == http.MethodHead && c.expectedStatus == http.StatusOK && rec.Body.Len() != 0 {
+			t.Fatalf("%s %s: expected no body, got %q", c.method, c.path, rec.Body.String())
+		}
+	}
+}
+
+func TestStreamingWeakETag(t *testing.T) {
+	fsys, err := NewWithOptions(EmbedFS, WithStreaming())
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := fsys.CompressionMiddleware(http.NotFoundHandler())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/testdata/compressed", nil))
+	etag := rec.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `W/"`) || !strings.HasSuffix(etag, `-4"`) {
+		t.Fatalf("expected a weak ETag including the size, got %q", etag)
+	}
+
+	for _, c := range []struct {
+		ifNoneMatch    string
+		expectedStatus int
+	}{
+		{etag, http.StatusNotModified},
+		{strings.TrimPrefix(etag, "W/"), http.StatusNotModified},
+		{`"other", ` + etag, http.StatusNotModified},
+		{"*", http.StatusNotModified},
+		{`"other"`, http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/testdata/compressed", nil)
+		req.Header.Set("If-None-Match", c.ifNoneMatch)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != c.expectedStatus {
+			t.Fatalf("%q: status is wrong, expected %d, got %d", c.ifNoneMatch, c.expectedStatus, rec.Code)
+		}
+		if c.expectedStatus == http.StatusNotModified && rec.Body.Len() != 0 {
+			t.Fatalf("%q: expected no body, got %q", c.ifNoneMatch, rec.Body.String())
+		}
+		if rec.Header().Get("ETag") != etag {
+			t.Fatalf("%q: ETag is wrong, expected %q, got %q", c.ifNoneMatch, etag, rec.Header().Get("ETag"))
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	stored, err := EmbedFS.ReadFile("testdata/compressed.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, handler := range []http.Handler{Handler(testFS), testFS} {
+		cases := []struct {
+			path             string
+			accept           string
+			expectedStatus   int
+			expectedEncoding string
+			expectedBody     []byte
+		}{
+			{path: "/testdata/compressed", accept: "gzip", expectedStatus: http.StatusOK, expectedEncoding: "gzip", expectedBody: stored},
+			{path: "/testdata/compressed", expectedStatus: http.StatusOK, expectedBody: []byte("foo\n")},
+			{path: "/testdata/missing", accept: "gzip", expectedStatus: http.StatusNotFound},
+		}
+		for _, c := range cases {
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			if c.accept != "" {
+				req.Header.Set("Accept-Encoding", c.accept)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != c.expectedStatus {
+				t.Fatalf("%s: status is wrong, expected %d, got %d", c.path, c.expectedStatus, rec.Code)
+			}
+			if enc := rec.Header().Get("Content-Encoding"); enc != c.expectedEncoding {
+				t.Fatalf("%s: encoding is wrong, expected %q, got %q", c.path, c.expectedEncoding, enc)
+			}
+			if c.expectedBody != nil && !bytes.Equal(rec.Body.Bytes(), c.expectedBody) {
+				t.Fatalf("%s: body is wrong, expected %q, got %q", c.path, c.expectedBody, rec.Body.Bytes())
+			}
+		}
+	}
+}
+
+func TestETag(t *testing.T) {
+	fsys := New(EmbedFS)
+	etag, err := fsys.ETag("testdata/compressed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sha256sum of the decompressed content.
+	if expected := `"b5bb9d8014a0f9b1d61e21e796d78dccdf1352f23cd32812f4850b878ae4944c"`; etag != expected {
+		t.Fatalf("ETag is wrong, expected %s, got %s", expected, etag)
+	}
+
+	rec := httptest.NewRecorder()
+	Handler(fsys).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/testdata/compressed", nil))
+	if sent := rec.Header().Get("ETag"); sent != etag {
+		t.Fatalf("expected the ETag %s to be sent, got %s", etag, sent)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/testdata/compressed", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	Handler(fsys).ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("status is wrong, expected %d, got %d", http.StatusNotModified, rec.Code)
+	}
+
+	if _, err := fsys.ETag("testdata/missing"); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
+
+func TestRelativeRef(t *testing.T) {
+	for _, c := range []struct {
+		name, target, expected string
+	}{
+		{"old.js", "app.js", "app.js"},
+		{"js/old.js", "js/app.js", "app.js"},
+		{"js/old.js", "app.js", "../app.js"},
+		{"old.js", "js/v2/app.js", "js/v2/app.js"},
+		{"a/b/old.js", "a/c/app.js", "../c/app.js"},
+		{"old.js", "a:b.js", "./a:b.js"},
+		{"old.js", "my app.js", "my%20app.js"},
+	} {
+		if actual := relativeRef(c.name, c.target); actual != c.expected {
+			t.Errorf("%s -> %s: expected %q, got %q", c.name, c.target, c.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+// identityEncoding is the content coding of files stored uncompressed.
+const identityEncoding = "identity"
+
+// fileIndex holds the codings each regular file is stored with, keyed by the
+// name it can be opened with, in order of preference. It either covers the
+// entire FS up front, or is built one directory at a time on first access.
+type fileIndex struct {
+	fsys fs.FS
+	lazy bool
+	// The stored codings recognized, in order of preference.
+	codings []storedEncoding
+	// The index of the entire FS, if not lazy.
+	all map[string][]string
+	// The indexes of the directories accessed so far, if lazy.
+	dirs sync.Map
+}
+
+// dirIndex is the lazily built index of a single directory.
+type dirIndex struct {
+	once    sync.Once
+	entries map[string][]string
+}
+
+// newIndex returns the index of fsys for the given stored codings, scanning
+// all of it right away unless lazy is set.
+func newIndex(fsys fs.FS, lazy bool, codings []storedEncoding) *fileIndex {
+	ix := &fileIndex{fsys: fsys, lazy: lazy, codings: codings}
+	if !lazy {
+		found := map[string]map[string]bool{}
+		fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+			if err == nil && d.Type().IsRegular() {
+				ix.addEncoding(found, name)
+			}
+			return nil
+		})
+		ix.all = orderEncodings(found)
+	}
+	return ix
+}
+
+// lookup returns the codings the file name is stored with, which must not be
+// modified.
+func (ix *fileIndex) lookup(name string) []string {
+	if ix == nil {
+		return nil
+	}
+	if !ix.lazy {
+		return ix.all[name]
+	}
+	dir, base := path.Split(name)
+	dir = strings.TrimSuffix(dir, "/")
+	if dir == "" {
+		dir = "."
+	}
+	v, _ := ix.dirs.LoadOrStore(dir, &dirIndex{})
+	di := v.(*dirIndex)
+	di.once.Do(func() {
+		found := map[string]map[string]bool{}
+		entries, _ := fs.ReadDir(ix.fsys, dir)
+		for _, entry := range entries {
+			if entry.Type().IsRegular() {
+				ix.addEncoding(found, entry.Name())
+			}
+		}
+		di.entries = orderEncodings(found)
+	})
+	return di.entries[base]
+}
+
+// addEncoding records the coding of the stored file name in found, under the
+// name it can be opened with.
+func (ix *fileIndex) addEncoding(found map[string]map[string]bool, name string) {
+	logical, encoding := name, identityEncoding
+	for _, se := range ix.codings {
+		if strings.HasSuffix(name, se.suffix) {
+			logical, encoding = strings.TrimSuffix(name, se.suffix), se.encoding
+			break
+		}
+	}
+	if found[logical] == nil {
+		found[logical] = map[string]bool{}
+	}
+	found[logical][encoding] = true
+}
+
+// orderEncodings returns the codings recorded by addEncoding as slices in
+// order of preference.
+func orderEncodings(found map[string]map[string]bool) map[string][]string {
+	index := make(map[string][]string, len(found))
+	for logical, encodings := range found {
+		for _, se := range storedEncodings {
+			if encodings[se.encoding] {
+				index[logical] = append(index[logical], se.encoding)
+			}
+		}
+		if encodings[identityEncoding] {
+			index[logical] = append(index[logical], identityEncoding)
+		}
+	}
+	return index
+}
+
+// AvailableEncodings returns the content codings the named file is stored
+// with, in order of preference, with "identity" standing for an uncompressed
+// copy. It returns nil if there is no such file. The codings are discovered
+// once when the FileSystem is created, or when first accessing the directory
+// with WithLazyIndexing, so this usually doesn't touch the embed FS.
+func (compressed FileSystem) AvailableEncodings(path string) []string {
+	path, err := compressed.resolvePath("stat", path)
+	if err != nil {
+		return nil
+	}
+	encodings := compressed.index.lookup(path)
+	if len(encodings) == 0 {
+		return nil
+	}
+	return append([]string(nil), encodings...)
+}
+
+// hasEncoding reports whether the file name is stored with the encoding.
+func (compressed FileSystem) hasEncoding(name, encoding string) bool {
+	for _, e := range compressed.index.lookup(name) {
+		if e == encoding {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestAvailableEncodings(t *testing.T) {
+	fsys, err := NewWithOptions(EmbedFS, WithAliases(map[string]string{"old.txt": "testdata/variants/data.txt"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string][]string{
+		"testdata/variants/data.txt": {"zstd", "gzip"},
+		"testdata/variants/only.txt": {"zstd"},
+		"testdata/both":              {"gzip", "identity"},
+		"testdata/compressed":        {"gzip"},
+		"testdata/uncompressed":      {"identity"},
+		"old.txt":                    {"zstd", "gzip"},
+		"testdata/compressed.gz":     nil,
+		"testdata/variants":          nil,
+		"testdata/missing":           nil,
+		"/testdata/both":             nil,
+	}
+	for path, expected := range cases {
+		if actual := fsys.AvailableEncodings(path); !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("encodings of %q are wrong, expected %q, got %q", path, expected, actual)
+		}
+	}
+
+	// The result is a copy, modifying it doesn't affect the index.
+	fsys.AvailableEncodings("testdata/both")[0] = "br"
+	if actual := fsys.AvailableEncodings("testdata/both"); actual[0] != "gzip" {
+		t.Fatalf("index was modified, got %q", actual)
+	}
+}
+
+func TestLazyIndexing(t *testing.T) {
+	lazy, err := NewWithOptions(EmbedFS, WithLazyIndexing())
+	if err != nil {
+		t.Fatal(err)
+	}
+	countDirs := func() int {
+		n := 0
+		lazy.index.dirs.Range(func(_, _ interface{}) bool {
+			n++
+			return true
+		})
+		return n
+	}
+	if n := countDirs(); n != 0 {
+		t.Fatalf("expected no directories to be indexed up front, got %d", n)
+	}
+
+	paths := []string{
+		"testdata/variants/data.txt",
+		"testdata/variants/only.txt",
+		"testdata/both",
+		"testdata/uncompressed",
+		"testdata/variants",
+		"testdata/missing",
+		"embed_gzip_test.go",
+	}
+	// Look up the same files concurrently, so that the race detector can
+	// catch unsynchronized index builds.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, path := range paths {
+				lazy.AvailableEncodings(path)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, path := range paths {
+		if expected, actual := testFS.AvailableEncodings(path), lazy.AvailableEncodings(path); !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("encodings of %q are wrong, expected %q, got %q", path, expected, actual)
+		}
+	}
+	// Only the directories looked up are indexed.
+	if n := countDirs(); n != 3 {
+		t.Fatalf("expected 3 directories to be indexed, got %d", n)
+	}
+
+	rec := httptest.NewRecorder()
+	lazy.CompressionMiddleware(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/testdata/docs/index.txt", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status is wrong, expected %d, got %d", http.StatusOK, rec.Code)
+	}
+}
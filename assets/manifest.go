@@ -0,0 +1,153 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Manifest returns the hex encoded hashes of the decompressed content of all
+// files, keyed by the names they can be opened with. SHA-256 is used unless
+// configured otherwise with WithHasher.
+func (compressed FileSystem) Manifest() (map[string]string, error) {
+	manifest := map[string]string{}
+	err := fs.WalkDir(compressed, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		sum, err := compressed.contentHash(name)
+		if err != nil {
+			return err
+		}
+		manifest[name] = sum
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// ChangedSince returns the sorted names of all files that were added or whose
+// content changed, compared to a manifest previously returned by Manifest.
+// Files which were removed since are not included, they can be found by
+// comparing the keys of both manifests.
+func (compressed FileSystem) ChangedSince(prev map[string]string) ([]string, error) {
+	manifest, err := compressed.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	var changed []string
+	for name, sum := range manifest {
+		if prevSum, ok := prev[name]; !ok || prevSum != sum {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// Duplicates returns the sorted names of files with identical decompressed
+// content, keyed by the hex encoded hash of that content. Only groups with more
+// than one file are included.
+func (compressed FileSystem) Duplicates() (map[string][]string, error) {
+	manifest, err := compressed.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	groups := map[string][]string{}
+	for name, sum := range manifest {
+		groups[sum] = append(groups[sum], name)
+	}
+	for sum, names := range groups {
+		if len(names) < 2 {
+			delete(groups, sum)
+			continue
+		}
+		sort.Strings(names)
+	}
+	return groups, nil
+}
+
+// contentHash returns the hex encoded hash of the decompressed content of the
+// named file.
+func (compressed FileSystem) contentHash(name string) (string, error) {
+	f, err := compressed.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := compressed.newHash()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// manifestEndpoint is the memoized manifest served at the path set with
+// WithManifestPath.
+type manifestEndpoint struct {
+	path string
+
+	once sync.Once
+	body []byte
+	etag string
+	err  error
+}
+
+// manifestEndpoint returns the endpoint set with WithManifestPath, if any.
+func (compressed FileSystem) manifestEndpoint() *manifestEndpoint {
+	if compressed.opts == nil {
+		return nil
+	}
+	return compressed.opts.manifest
+}
+
+// serveManifest writes the fingerprinted names of all files as JSON to w.
+func (compressed FileSystem) serveManifest(w http.ResponseWriter, r *http.Request, m *manifestEndpoint) {
+	m.once.Do(func() {
+		hashes, err := compressed.Manifest()
+		if err != nil {
+			m.err = err
+			return
+		}
+		manifest := make(map[string]string, len(hashes))
+		for name, sum := range hashes {
+			manifest[name] = fingerprintedName(name, sum)
+		}
+		// Map keys are sorted, making the output deterministic.
+		if m.body, m.err = json.Marshal(manifest); m.err != nil {
+			return
+		}
+		h := compressed.newHash()
+		h.Write(m.body)
+		m.etag = `"` + compressed.etagSum(h) + `"`
+	})
+	if m.err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("ETag", m.etag)
+	http.ServeContent(w, r, m.path, time.Time{}, bytes.NewReader(m.body))
+}
@@ -0,0 +1,180 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"embed"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+//go:embed testdata/templates
+var templatesFS embed.FS
+
+func TestManifest(t *testing.T) {
+	manifest, err := New(templatesFS).Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		// sha256sum of the decompressed content.
+		"testdata/templates/greeting.tmpl": "e2a182d4e00d4c6f5f72f9c1c140bba6272a00e295aa4eae1883e284b9ff60a3",
+		"testdata/templates/page.tmpl":     "ff962c9ee32589366ab0dff028f86fd0ec46e382334342e042302b4a3d5bf4d6",
+	}
+	if !reflect.DeepEqual(manifest, expected) {
+		t.Fatalf("manifest is wrong, expected %v, got %v", expected, manifest)
+	}
+}
+
+func TestManifestDecodeError(t *testing.T) {
+	if _, err := brokenFS.Manifest(); err == nil {
+		t.Fatal("expected an error for the corrupt file")
+	}
+}
+
+func TestChangedSince(t *testing.T) {
+	fsys := New(templatesFS)
+	manifest, err := fsys.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := fsys.ChangedSince(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 0 {
+		t.Fatalf("expected no changes, got %v", changed)
+	}
+
+	prev := map[string]string{
+		"testdata/templates/greeting.tmpl": manifest["testdata/templates/greeting.tmpl"],
+		"testdata/templates/page.tmpl":     "stale",
+		"testdata/templates/removed.tmpl":  "removed",
+	}
+	changed, err = fsys.ChangedSince(prev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"testdata/templates/page.tmpl"}; !reflect.DeepEqual(changed, expected) {
+		t.Fatalf("changes are wrong, expected %v, got %v", expected, changed)
+	}
+
+	changed, err = fsys.ChangedSince(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"testdata/templates/greeting.tmpl", "testdata/templates/page.tmpl"}; !reflect.DeepEqual(changed, expected) {
+		t.Fatalf("additions are wrong, expected %v, got %v", expected, changed)
+	}
+}
+
+func TestManifestPath(t *testing.T) {
+	fsys, err := NewWithOptions(templatesFS, WithManifestPath("/asset-manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := fsys.CompressionMiddleware(http.NotFoundHandler())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/asset-manifest.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status is wrong, expected %d, got %d", http.StatusOK, rec.Code)
+	}
+	if ctype := rec.Header().Get("Content-Type"); ctype != "application/json" {
+		t.Fatalf("content type is wrong, got %q", ctype)
+	}
+	var served map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &served); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"testdata/templates/greeting.tmpl": "testdata/templates/greeting.e2a182d4.tmpl",
+		"testdata/templates/page.tmpl":     "testdata/templates/page.ff962c9e.tmpl",
+	}
+	if !reflect.DeepEqual(served, expected) {
+		t.Fatalf("manifest is wrong, expected %v, got %v", expected, served)
+	}
+
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag")
+	}
+	req := httptest.NewRequest(http.MethodGet, "/asset-manifest.json", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("status is wrong, expected %d, got %d", http.StatusNotModified, rec.Code)
+	}
+
+	// Files are served under their fingerprinted names, as well as usual.
+	for _, target := range []string{"/testdata/templates/page.ff962c9e.tmpl", "/testdata/templates/page.tmpl"} {
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status of %s is wrong, expected %d, got %d", target, http.StatusOK, rec.Code)
+		}
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/testdata/templates/page.00000000.tmpl", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected a stale fingerprint not to be found, got %d", rec.Code)
+	}
+}
+
+func TestManifestPathErrors(t *testing.T) {
+	if _, err := NewWithOptions(templatesFS, WithManifestPath("asset-manifest.json")); err == nil {
+		t.Fatal("expected an error for a relative path")
+	}
+
+	fsys, err := NewWithOptions(brokenEmbedFS, WithManifestPath("/asset-manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	fsys.CompressionMiddleware(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/asset-manifest.json", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected an error for the corrupt file, got %d", rec.Code)
+	}
+}
+
+//go:embed testdata/duplicates
+var duplicatesFS embed.FS
+
+func TestDuplicates(t *testing.T) {
+	duplicates, err := New(duplicatesFS).Duplicates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		// sha256sum of "shared\n".
+		"cf99975aa7995fad86fae7f3b0905143f30a52501944dff26002afc99c3b8419": {
+			"testdata/duplicates/a.txt",
+			"testdata/duplicates/vendor/a.txt",
+		},
+	}
+	if !reflect.DeepEqual(duplicates, expected) {
+		t.Fatalf("duplicates are wrong, expected %v, got %v", expected, duplicates)
+	}
+
+	if _, err := brokenFS.Duplicates(); err == nil {
+		t.Fatal("expected an error for the corrupt file")
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+)
+
+// Materialize decompresses all files up front and returns them as a read-only
+// in-memory fs.FS, keyed by the names they can be opened with. Reading from it
+// never decodes anything, at the cost of holding all content in memory, which
+// suits small sets of very hot assets. It is safe for concurrent use.
+func (compressed FileSystem) Materialize() (fs.FS, error) {
+	mem := memFS{}
+	err := fs.WalkDir(compressed, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// The info of the stored file, which unlike that of the logical file
+		// doesn't require decoding it.
+		var info fs.FileInfo
+		if e, ok := d.(DirEntry); ok {
+			info, err = e.DirEntry.Info()
+		} else {
+			info, err = d.Info()
+		}
+		if err != nil {
+			return err
+		}
+		node := &memNode{info: memInfo{name: path.Base(name), mode: info.Mode(), modTime: info.ModTime()}}
+		if !d.IsDir() {
+			if node.data, err = fs.ReadFile(compressed, name); err != nil {
+				return err
+			}
+			node.info.size = int64(len(node.data))
+		}
+		mem[name] = node
+		if name != "." {
+			parent := mem[path.Dir(name)]
+			parent.entries = append(parent.entries, fs.FileInfoToDirEntry(node.info))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range mem {
+		sort.Slice(node.entries, func(i, j int) bool { return node.entries[i].Name() < node.entries[j].Name() })
+	}
+	return mem, nil
+}
+
+// memFS is the read-only fs.FS returned by Materialize.
+type memFS map[string]*memNode
+
+type memNode struct {
+	info memInfo
+	// The content of a file.
+	data []byte
+	// The sorted entries of a directory.
+	entries []fs.DirEntry
+}
+
+// Open implements the fs.FS interface.
+func (m memFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	node, ok := m[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if node.info.IsDir() {
+		return &memDir{node: node, path: name}, nil
+	}
+	return &memFile{node: node, Reader: bytes.NewReader(node.data)}, nil
+}
+
+// memInfo describes a file or directory of a memFS.
+type memInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return i.size }
+func (i memInfo) Mode() fs.FileMode  { return i.mode }
+func (i memInfo) ModTime() time.Time { return i.modTime }
+func (i memInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i memInfo) Sys() interface{}   { return nil }
+
+// memFile is an open file of a memFS.
+type memFile struct {
+	*bytes.Reader
+	node *memNode
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.node.info, nil }
+func (f *memFile) Close() error               { return nil }
+
+// memDir is an open directory of a memFS.
+type memDir struct {
+	node   *memNode
+	path   string
+	offset int
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.node.info, nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.path, Err: fs.ErrInvalid}
+}
+
+// ReadDir implements the fs.ReadDirFile interface.
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	entries := d.node.entries[d.offset:]
+	if n > 0 && len(entries) > n {
+		entries = entries[:n]
+	}
+	if n > 0 && len(entries) == 0 {
+		return nil, io.EOF
+	}
+	d.offset += len(entries)
+	return append([]fs.DirEntry(nil), entries...), nil
+}
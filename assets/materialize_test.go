@@ -0,0 +1,69 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMaterialize(t *testing.T) {
+	fsys := New(templatesFS)
+	materialized, err := fsys.Materialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fstest.TestFS(materialized, "testdata/templates/greeting.tmpl", "testdata/templates/page.tmpl"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"testdata/templates/greeting.tmpl", "testdata/templates/page.tmpl"} {
+		expected, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err := fs.ReadFile(materialized, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(actual) != string(expected) {
+			t.Fatalf("content of %s is wrong, expected %q, got %q", name, expected, actual)
+		}
+	}
+	if _, err := materialized.Open("testdata/templates/greeting.tmpl.gz"); err == nil {
+		t.Fatal("expected the compressed name to not exist")
+	}
+}
+
+func TestMaterializeDecodesOnce(t *testing.T) {
+	opened := map[string]int{}
+	fsys := New(templatesFS).WithAccessLog(func(_, path string, found bool) {
+		opened[path]++
+	})
+	if _, err := fsys.Materialize(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"testdata/templates/greeting.tmpl", "testdata/templates/page.tmpl"} {
+		if opened[name] != 1 {
+			t.Errorf("expected %s to be opened once, got %d", name, opened[name])
+		}
+	}
+}
+
+func TestMaterializeDecodeError(t *testing.T) {
+	if _, err := brokenFS.Materialize(); err == nil {
+		t.Fatal("expected an error for the corrupt file")
+	}
+}
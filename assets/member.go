@@ -0,0 +1,80 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+)
+
+// OpenMember opens the decompressed content of the member with the given
+// index of a file stored as a multi-member gzip stream, counting from zero.
+// Unlike Open, which returns the content of all members concatenated, this
+// allows storing e.g. an index next to the data in a single file. Earlier
+// members still have to be decompressed to find where the requested one
+// starts, so reading a late member of a large file is as costly as reading
+// the entire file. An error wrapping fs.ErrNotExist is returned if the file
+// has no such member.
+func (compressed FileSystem) OpenMember(path string, index int) (fs.File, error) {
+	path, err := compressed.resolve("open", path)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 {
+		return nil, &fs.PathError{Op: "open", Path: path, Err: fs.ErrInvalid}
+	}
+	f, err := compressed.embed.Open(path + gzipSuffix)
+	if err != nil {
+		if pathErr, ok := err.(*fs.PathError); ok {
+			pathErr.Path = path
+		}
+		return nil, err
+	}
+
+	// The gzip reader must read from an io.ByteReader, so that it doesn't
+	// consume any data past the end of each member.
+	br := bufio.NewReader(f)
+	gr, err := getGzipReader(br)
+	if err != nil {
+		f.Close()
+		return nil, compressed.decodeError(path+gzipSuffix, err)
+	}
+	defer putGzipReader(gr)
+	for i := 0; ; i++ {
+		gr.Multistream(false)
+		if i == index {
+			break
+		}
+		if _, err := io.Copy(io.Discard, gr); err != nil {
+			f.Close()
+			return nil, compressed.decodeError(path+gzipSuffix, err)
+		}
+		if err := gr.Reset(br); err != nil {
+			f.Close()
+			if errors.Is(err, io.EOF) {
+				return nil, &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+			}
+			return nil, compressed.decodeError(path+gzipSuffix, err)
+		}
+	}
+
+	c, err := io.ReadAll(gr)
+	if err != nil {
+		f.Close()
+		return nil, compressed.decodeError(path+gzipSuffix, err)
+	}
+	return &File{file: f, content: c}, nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+)
+
+func TestOpenMember(t *testing.T) {
+	for index, expected := range []string{"first member\n", "second\n"} {
+		f, err := testFS.OpenMember("testdata/multistream", index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stat, err := f.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		if string(content) != expected {
+			t.Fatalf("content of member %d is wrong, expected %q, got %q", index, expected, content)
+		}
+		if stat.Size() != int64(len(expected)) {
+			t.Fatalf("size of member %d is wrong, expected %d, got %d", index, len(expected), stat.Size())
+		}
+	}
+}
+
+func TestOpenMemberErrors(t *testing.T) {
+	for _, c := range []struct {
+		path  string
+		index int
+		err   error
+	}{
+		{"testdata/multistream", 2, fs.ErrNotExist},
+		{"testdata/multistream", -1, fs.ErrInvalid},
+		{"testdata/uncompressed", 0, fs.ErrNotExist},
+		{"testdata/missing", 0, fs.ErrNotExist},
+	} {
+		if _, err := testFS.OpenMember(c.path, c.index); !errors.Is(err, c.err) {
+			t.Fatalf("expected %v for member %d of %s, got %v", c.err, c.index, c.path, err)
+		}
+	}
+
+	var decodeErr *DecodeError
+	if _, err := brokenFS.OpenMember("testdata_broken/corrupt", 0); !errors.As(err, &decodeErr) {
+		t.Fatalf("expected a DecodeError for the corrupt file, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+type mount struct {
+	prefix  string
+	handler http.Handler
+}
+
+// Mount returns a handler serving the FileSystems of mounts under their URL
+// path prefixes, e.g. "/ui". Requests are dispatched to the mount with the
+// longest matching prefix, which is stripped from the path before looking up
+// the file. A mount at "/" or "" serves all requests not matching any other
+// prefix, without one unmatched requests are answered with 404 Not Found.
+// Trailing slashes of prefixes are ignored, Mount panics if two prefixes only
+// differ in them, like "/ui" and "/ui/", as either could serve the requests.
+func Mount(mounts map[string]FileSystem) http.Handler {
+	sorted := make([]mount, 0, len(mounts))
+	seen := make(map[string]string, len(mounts))
+	for prefix, fsys := range mounts {
+		normalized := strings.TrimRight(prefix, "/")
+		if other, ok := seen[normalized]; ok {
+			panic(fmt.Sprintf("assets: mount prefixes %q and %q both normalize to %q", other, prefix, normalized))
+		}
+		seen[normalized] = prefix
+		sorted = append(sorted, mount{
+			prefix:  normalized,
+			handler: fsys.CompressionMiddleware(http.NotFoundHandler()),
+		})
+	}
+	// Try longer prefixes first.
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i].prefix) > len(sorted[j].prefix) })
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, m := range sorted {
+			rest, ok := matchPrefix(r.URL.Path, m.prefix)
+			if !ok {
+				continue
+			}
+			r2 := new(http.Request)
+			*r2 = *r
+			r2.URL = new(url.URL)
+			*r2.URL = *r.URL
+			r2.URL.Path = rest
+			r2.URL.RawPath = ""
+			m.handler.ServeHTTP(w, r2)
+			return
+		}
+		http.NotFound(w, r)
+	})
+}
+
+// matchPrefix reports whether the URL path p lies below prefix, which must
+// not end in a slash, returning the rest of the path.
+func matchPrefix(p, prefix string) (string, bool) {
+	if prefix == "" {
+		return p, true
+	}
+	if p == prefix {
+		return "/", true
+	}
+	if rest := strings.TrimPrefix(p, prefix); rest != p && strings.HasPrefix(rest, "/") {
+		return rest, true
+	}
+	return "", false
+}
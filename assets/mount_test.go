@@ -0,0 +1,157 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"embed"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+var (
+	//go:embed testdata/docs
+	docsFS embed.FS
+	//go:embed testdata/docs/api
+	apiFS embed.FS
+)
+
+func TestMount(t *testing.T) {
+	mounts := map[string]FileSystem{
+		"/docs":      New(docsFS),
+		"/docs/api/": New(apiFS),
+	}
+	withFallback := map[string]FileSystem{"/": testFS}
+	for prefix, fsys := range mounts {
+		withFallback[prefix] = fsys
+	}
+
+	cases := []struct {
+		name           string
+		mounts         map[string]FileSystem
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "prefix",
+			mounts:         mounts,
+			path:           "/docs/testdata/docs/index.txt",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "docs\n",
+		},
+		{
+			name:           "longer overlapping prefix",
+			mounts:         mounts,
+			path:           "/docs/api/testdata/docs/api/index.txt",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "api\n",
+		},
+		{
+			name:           "shorter overlapping prefix",
+			mounts:         mounts,
+			path:           "/docs/testdata/docs/api/index.txt",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "api\n",
+		},
+		{
+			name:           "missing file in matching mount",
+			mounts:         withFallback,
+			path:           "/docs/testdata/compressed",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "partial prefix match",
+			mounts:         mounts,
+			path:           "/docsx/testdata/docs/index.txt",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "no matching mount",
+			mounts:         mounts,
+			path:           "/testdata/compressed",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "fallback mount",
+			mounts:         withFallback,
+			path:           "/testdata/compressed",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "foo\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			Mount(c.mounts).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, c.path, nil))
+			if rec.Code != c.expectedStatus {
+				t.Fatalf("status is wrong, expected %d, got %d", c.expectedStatus, rec.Code)
+			}
+			if c.expectedStatus == http.StatusOK && rec.Body.String() != c.expectedBody {
+				t.Fatalf("body is wrong, expected %q, got %q", c.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestMountRedirectAliases(t *testing.T) {
+	fsys, err := NewWithOptions(docsFS,
+		WithAliases(map[string]string{
+			"latest.txt":        "testdata/docs/index.txt",
+			"testdata/docs/old": "testdata/docs/index.txt",
+		}),
+		WithRedirectAliases(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := Mount(map[string]FileSystem{"/ui": fsys})
+
+	for requested, expected := range map[string]string{
+		"/ui/latest.txt":        "/ui/testdata/docs/index.txt",
+		"/ui/testdata/docs/old": "/ui/testdata/docs/index.txt",
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, requested, nil))
+		if rec.Code != http.StatusMovedPermanently {
+			t.Fatalf("%s: status is wrong, expected %d, got %d", requested, http.StatusMovedPermanently, rec.Code)
+		}
+		base, err := url.Parse("http://example.com" + requested)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ref, err := url.Parse(rec.Header().Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if location := base.ResolveReference(ref).Path; location != expected {
+			t.Fatalf("%s: expected a redirect to %s, got %s", requested, expected, location)
+		}
+	}
+}
+
+func TestMountDuplicatePrefixes(t *testing.T) {
+	for _, prefixes := range [][2]string{{"", "/"}, {"/docs", "/docs/"}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected a panic for the prefixes %q", prefixes)
+				}
+			}()
+			Mount(map[string]FileSystem{prefixes[0]: New(docsFS), prefixes[1]: New(apiFS)})
+		}()
+	}
+}
@@ -0,0 +1,709 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"errors"
+	"fmt"
+	"hash"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Option configures a FileSystem created by NewWithOptions.
+type Option func(*options) error
+
+type options struct {
+	name            string
+	requireEncoding string
+	faultInjector   func(path string) error
+	aliases         map[string]string
+	redirectAliases bool
+	normalizePaths  bool
+	streaming       bool
+	gzipOnly        bool
+	streamThreshold int64
+	hasher          func() hash.Hash
+	hasherName      string
+	cleanURLs       bool
+	preloadLinks    map[string][]string
+	noDecompress    map[string]bool
+	concatSep       *string
+	immutable       *regexp.Regexp
+	readBufferSize  int
+	proxyFriendly   bool
+	timings         *decodeTimings
+	lazyIndexing    bool
+	preferSmallest  bool
+	manifest        *manifestEndpoint
+	caseInsensitive bool
+	securityHeaders map[string]string
+	rangeIndex      map[string][]rangeMember
+	responseHook    func(w http.ResponseWriter, r *http.Request, path string, info fs.FileInfo)
+	variantSelector func(r *http.Request, candidates []string) string
+	selectVariants  bool
+	accessLog       func(fsName, path string, found bool)
+	sizeFunc        func(path string) (int64, bool)
+	decodeSlots     chan struct{}
+	decodeOrder     []string
+	cache           *decodeCache
+	// The canonical names of all files and directories, keyed by their
+	// lowercased names, if caseInsensitive is set.
+	folded map[string]string
+}
+
+// NewWithOptions returns a FileSystem for the given embed FS, configured by
+// opts. An error is returned if any of the options is invalid, or if one of
+// the decoders registered with RegisterDecoder doesn't work.
+func NewWithOptions(fs embed.FS, opts ...Option) (FileSystem, error) {
+	if err := checkDecoders(); err != nil {
+		return FileSystem{}, err
+	}
+	compressed := newFileSystem(fs)
+	for _, opt := range opts {
+		if err := opt(compressed.opts); err != nil {
+			return FileSystem{}, err
+		}
+	}
+	compressed.index = newIndex(fs, compressed.opts.lazyIndexing, compressed.codings())
+	if compressed.opts.caseInsensitive {
+		folded, err := compressed.foldNames()
+		if err != nil {
+			return FileSystem{}, err
+		}
+		compressed.opts.folded = folded
+	}
+	if compressed.opts.requireEncoding != "" {
+		if err := compressed.checkEncoding(compressed.opts.requireEncoding); err != nil {
+			return FileSystem{}, err
+		}
+	}
+	for name, deps := range compressed.opts.preloadLinks {
+		for _, n := range append([]string{name}, deps...) {
+			if !compressed.isFile(n) {
+				return FileSystem{}, fmt.Errorf("assets: preload link file %q does not exist", n)
+			}
+		}
+	}
+	return compressed, nil
+}
+
+// WithName sets a name identifying the FileSystem in errors, cache callbacks
+// and the access log, which helps telling them apart when several FileSystems
+// are used by the same program.
+func WithName(name string) Option {
+	return func(o *options) error {
+		if name == "" {
+			return errors.New("assets: FileSystem name must not be empty")
+		}
+		o.name = name
+		return nil
+	}
+}
+
+// fsName returns the name set with WithName, if any.
+func (compressed FileSystem) fsName() string {
+	if compressed.opts == nil {
+		return ""
+	}
+	return compressed.opts.name
+}
+
+// WithRequireEncoding makes NewWithOptions fail unless every regular file is
+// stored with the given content coding, i.e. "gzip", "zstd" or "br". For
+// "identity", no file may be stored compressed at all. This turns accidentally
+// (un)compressed assets into an error at startup.
+func WithRequireEncoding(encoding string) Option {
+	return func(o *options) error {
+		if encoding != identityEncoding && suffixFor(encoding) == "" {
+			return fmt.Errorf("assets: unknown encoding %q", encoding)
+		}
+		o.requireEncoding = encoding
+		return nil
+	}
+}
+
+// checkEncoding returns an error listing all regular files that aren't stored
+// with the given encoding.
+func (compressed FileSystem) checkEncoding(encoding string) error {
+	var offending []string
+	err := fs.WalkDir(compressed.embed, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		stored := identityEncoding
+		for _, se := range storedEncodings {
+			if strings.HasSuffix(name, se.suffix) {
+				stored = se.encoding
+			}
+		}
+		if stored != encoding {
+			offending = append(offending, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(offending) > 0 {
+		return fmt.Errorf("assets: files not stored with encoding %s: %s", encoding, strings.Join(offending, ", "))
+	}
+	return nil
+}
+
+// WithFaultInjector makes Open return the error returned by fn for a path, if
+// any, before trying to decode the file. This is primarily meant for testing
+// the handling of broken assets without having to embed broken files.
+func WithFaultInjector(fn func(path string) error) Option {
+	return func(o *options) error {
+		o.faultInjector = fn
+		return nil
+	}
+}
+
+// WithAliases makes the FileSystem resolve the keys of aliases to the files
+// named by their values, e.g. to keep serving files after they got renamed.
+// Leading slashes are ignored, so that URL paths can be used.
+func WithAliases(aliases map[string]string) Option {
+	return func(o *options) error {
+		resolved := make(map[string]string, len(aliases))
+		for alias, target := range aliases {
+			alias, target = strings.TrimPrefix(alias, "/"), strings.TrimPrefix(target, "/")
+			if !fs.ValidPath(alias) || !fs.ValidPath(target) {
+				return fmt.Errorf("assets: invalid alias %q for %q", alias, target)
+			}
+			resolved[alias] = target
+		}
+		o.aliases = resolved
+		return nil
+	}
+}
+
+// WithRedirectAliases makes CompressionMiddleware answer requests for aliases
+// set with WithAliases with a permanent redirect to their target, instead of
+// serving the target directly. The Location is relative to the requested URL,
+// so that redirects stay below the prefix of a Mount. Open is not affected.
+func WithRedirectAliases() Option {
+	return func(o *options) error {
+		o.redirectAliases = true
+		return nil
+	}
+}
+
+// WithPathNormalization makes the FileSystem accept paths using backslashes as
+// separators, as produced by the filepath package on Windows, by converting
+// them to forward slashes. This isn't the default, as backslashes are valid
+// in file names on other platforms.
+func WithPathNormalization() Option {
+	return func(o *options) error {
+		o.normalizePaths = true
+		return nil
+	}
+}
+
+// WithStreaming makes Open return files that decompress their content while
+// being read, instead of buffering all of it up front. This keeps the memory
+// needed for serving large files bounded, at the cost of not being able to
+// seek. The size of such files is taken from their gzip trailer, which is
+// only accurate for files consisting of a single gzip member smaller than
+// 4GiB.
+func WithStreaming() Option {
+	return func(o *options) error {
+		o.streaming = true
+		return nil
+	}
+}
+
+// WithStreamingThreshold makes Open return streaming files, as with
+// WithStreaming, only for files whose decompressed size is at least n bytes,
+// while smaller files are buffered and remain seekable. The size is taken
+// from the function set with WithSizeFunc, such as the Size of a
+// CompressManifest, or else from the gzip trailer. Files of unknown size are
+// streamed.
+func WithStreamingThreshold(n int64) Option {
+	return func(o *options) error {
+		if n <= 0 {
+			return fmt.Errorf("assets: streaming threshold must be positive, got %d", n)
+		}
+		o.streamThreshold = n
+		return nil
+	}
+}
+
+// WithReadBufferSize sets the size of the chunks streaming files pull from
+// their decompressor when copied with WriteTo, e.g. by io.Copy, trading
+// throughput for memory. It defaults to 32KiB, like io.Copy.
+func WithReadBufferSize(n int) Option {
+	return func(o *options) error {
+		if n <= 0 {
+			return fmt.Errorf("assets: read buffer size must be positive, got %d", n)
+		}
+		o.readBufferSize = n
+		return nil
+	}
+}
+
+// WithCleanURLs makes CompressionMiddleware resolve requests for missing files
+// without an extension to HTML files, like "/guide/intro" to "guide/intro.html"
+// or, failing that, "guide/intro/index.html".
+func WithCleanURLs() Option {
+	return func(o *options) error {
+		o.cleanURLs = true
+		return nil
+	}
+}
+
+// WithPreloadLinks makes CompressionMiddleware send a
+// "Link: <dep>; rel=preload" header for each of the dependencies listed for a
+// file when serving it, so that clients can start fetching them right away.
+// The "as" attribute is set according to the content type of each dependency.
+// Leading slashes are ignored, so that URL paths can be used. All files must
+// exist.
+func WithPreloadLinks(links map[string][]string) Option {
+	return func(o *options) error {
+		resolved := make(map[string][]string, len(links))
+		for name, deps := range links {
+			name = strings.TrimPrefix(name, "/")
+			if !fs.ValidPath(name) {
+				return fmt.Errorf("assets: invalid preload link file %q", name)
+			}
+			for _, dep := range deps {
+				dep = strings.TrimPrefix(dep, "/")
+				if !fs.ValidPath(dep) {
+					return fmt.Errorf("assets: invalid preload link %q for %q", dep, name)
+				}
+				resolved[name] = append(resolved[name], dep)
+			}
+		}
+		o.preloadLinks = resolved
+		return nil
+	}
+}
+
+// WithNoDecompressExtensions makes CompressionMiddleware never decompress files
+// with one of the given extensions, e.g. ".png", as their content is
+// incompressible and decoding them is wasted work. Clients accepting the
+// coding get the stored bytes as usual, others get them as they are, without a
+// Content-Encoding and with the media type of the coding, e.g.
+// application/gzip, rather than the decoded file. Dynamic responses for such
+// paths aren't compressed either. Extensions are matched
+// case-insensitively, the leading dot is optional.
+func WithNoDecompressExtensions(exts ...string) Option {
+	return func(o *options) error {
+		o.noDecompress = make(map[string]bool, len(exts))
+		for _, ext := range exts {
+			if ext == "" || ext == "." {
+				return errors.New("assets: extension must not be empty")
+			}
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			o.noDecompress[strings.ToLower(ext)] = true
+		}
+		return nil
+	}
+}
+
+// skipsDecompression reports whether name has an extension set with
+// WithNoDecompressExtensions.
+func (compressed FileSystem) skipsDecompression(name string) bool {
+	if compressed.opts == nil || len(compressed.opts.noDecompress) == 0 {
+		return false
+	}
+	return compressed.opts.noDecompress[strings.ToLower(path.Ext(name))]
+}
+
+// WithConcatSeparator sets the separator Concat puts between files, which
+// defaults to a newline.
+func WithConcatSeparator(sep string) Option {
+	return func(o *options) error {
+		o.concatSep = &sep
+		return nil
+	}
+}
+
+// WithImmutablePattern makes CompressionMiddleware treat files whose names
+// match re, e.g. fingerprinted ones like "app.3f9a1c.js", as never changing.
+// They are served with "Cache-Control: public, max-age=31536000, immutable",
+// and any conditional request for them is answered with 304 Not Modified
+// right away, without computing an ETag.
+func WithImmutablePattern(re *regexp.Regexp) Option {
+	return func(o *options) error {
+		if re == nil {
+			return errors.New("assets: immutable pattern must not be nil")
+		}
+		o.immutable = re
+		return nil
+	}
+}
+
+// isImmutable reports whether name matches the pattern set with
+// WithImmutablePattern.
+func (compressed FileSystem) isImmutable(name string) bool {
+	return compressed.opts != nil && compressed.opts.immutable != nil && compressed.opts.immutable.MatchString(name)
+}
+
+// WithProxyFriendlyHeaders makes CompressionMiddleware normalize the headers
+// of all responses for the benefit of reverse proxies: Content-Encoding is
+// collapsed into a single value, Date is set if missing, and Transfer-Encoding
+// is removed, leaving it to the HTTP server.
+func WithProxyFriendlyHeaders() Option {
+	return func(o *options) error {
+		o.proxyFriendly = true
+		return nil
+	}
+}
+
+// WithTimingCollection makes the FileSystem record how long decompressing each
+// file took when opening it, as reported by DecodeTimings. Files opened in
+// streaming mode are decompressed while being read and aren't recorded.
+func WithTimingCollection() Option {
+	return func(o *options) error {
+		o.timings = &decodeTimings{durations: map[string]*timingWindow{}}
+		return nil
+	}
+}
+
+// WithLazyIndexing makes the FileSystem discover the codings files are stored
+// with one directory at a time, when a file in it is first looked up, rather
+// than scanning the entire embed FS when it is created. This speeds up startup
+// for large trees of which only a few files are used.
+func WithLazyIndexing() Option {
+	return func(o *options) error {
+		o.lazyIndexing = true
+		return nil
+	}
+}
+
+// WithPreferSmallest makes CompressionMiddleware pass through whichever of the
+// stored variants of a file the client accepts is the smallest, rather than
+// the one it prefers. Clients accepting none of them get the decompressed
+// content as usual.
+func WithPreferSmallest() Option {
+	return func(o *options) error {
+		o.preferSmallest = true
+		return nil
+	}
+}
+
+// WithManifestPath makes CompressionMiddleware serve a JSON object mapping the
+// names of all files to their fingerprinted names, as returned by
+// FingerprintedName, at the given URL path, e.g. "/asset-manifest.json",
+// instead of a stored file. It is generated on first request, and carries an
+// ETag derived from its content, so that clients can cheaply revalidate it.
+// The FileSystem resolves the fingerprinted names to the files they were
+// derived from, as long as the fingerprint matches their content.
+func WithManifestPath(path string) Option {
+	return func(o *options) error {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("assets: manifest path %q must start with a slash", path)
+		}
+		o.manifest = &manifestEndpoint{path: path}
+		return nil
+	}
+}
+
+// WithCaseInsensitive makes the FileSystem resolve names regardless of their
+// case, e.g. "Logo.PNG" to "logo.png". NewWithOptions fails if two names only
+// differ in case, as it would be ambiguous which of them to resolve to.
+func WithCaseInsensitive() Option {
+	return func(o *options) error {
+		o.caseInsensitive = true
+		return nil
+	}
+}
+
+// foldNames returns the canonical names of all files and directories, keyed by
+// their lowercased names.
+func (compressed FileSystem) foldNames() (map[string]string, error) {
+	folded := map[string]string{}
+	err := fs.WalkDir(compressed, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		lower := strings.ToLower(name)
+		if other, ok := folded[lower]; ok && other != name {
+			return fmt.Errorf("assets: names %q and %q only differ in case", other, name)
+		}
+		folded[lower] = name
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return folded, nil
+}
+
+// foldCase returns the canonical name of name, if resolving names case
+// insensitively as set with WithCaseInsensitive.
+func (compressed FileSystem) foldCase(name string) string {
+	if compressed.opts == nil || compressed.opts.folded == nil {
+		return name
+	}
+	if canonical, ok := compressed.opts.folded[strings.ToLower(name)]; ok {
+		return canonical
+	}
+	return name
+}
+
+// WithSecurityHeaders makes CompressionMiddleware add the given headers, e.g.
+// a Content-Security-Policy, to all GET and HEAD responses for files, along
+// with "X-Content-Type-Options: nosniff" unless overridden. Setting a header to
+// an empty value omits it. Headers already set by an outer handler are kept.
+func WithSecurityHeaders(headers map[string]string) Option {
+	return func(o *options) error {
+		o.securityHeaders = map[string]string{"X-Content-Type-Options": "nosniff"}
+		for k, v := range headers {
+			k = http.CanonicalHeaderKey(k)
+			if v == "" {
+				delete(o.securityHeaders, k)
+				continue
+			}
+			o.securityHeaders[k] = v
+		}
+		return nil
+	}
+}
+
+// addSecurityHeaders adds the headers set with WithSecurityHeaders to h, unless
+// already set.
+func (compressed FileSystem) addSecurityHeaders(h http.Header) {
+	if compressed.opts == nil {
+		return
+	}
+	for k, v := range compressed.opts.securityHeaders {
+		if _, ok := h[k]; !ok {
+			h.Set(k, v)
+		}
+	}
+}
+
+// WithResponseHook makes CompressionMiddleware call hook for every file it is
+// about to send, after setting the standard headers but before writing the
+// body, e.g. to set additional headers. path is the name of the file, and info
+// describes it as sent, i.e. the stored variant if passed through compressed.
+// Changes to Content-Length and Content-Encoding are reverted, as they would
+// corrupt the response. The hook isn't called for error responses.
+func WithResponseHook(hook func(w http.ResponseWriter, r *http.Request, path string, info fs.FileInfo)) Option {
+	return func(o *options) error {
+		o.responseHook = hook
+		return nil
+	}
+}
+
+// WithVariantSelector makes CompressionMiddleware choose between several stored
+// copies of a file in the negotiated coding, e.g. "app.js.gz" compressed fast
+// and "app.js.max.gz" compressed best, by calling selector with the names of
+// all of them, i.e. the usual one and those with an additional extension before
+// the coding suffix. It returns the name to send, typically depending on hints
+// of the request like "Save-Data". If selector is nil, the smallest copy is
+// sent. As any additional extension matches, files like "app.js.map.gz" are
+// candidates as well, so this must only be used where that's not a problem.
+func WithVariantSelector(selector func(r *http.Request, candidates []string) string) Option {
+	return func(o *options) error {
+		o.variantSelector = selector
+		o.selectVariants = true
+		return nil
+	}
+}
+
+// WithSizeFunc makes the FileSystem ask fn for the decompressed size of files
+// where it otherwise could only be determined from the gzip trailer, or not at
+// all without decoding them. This is the case for the Stat of files opened in
+// streaming mode, OpenRawWithSize and ContentLength. If fn returns false, the
+// size is determined as usual. This allows taking sizes from a manifest
+// produced when compressing the files.
+func WithSizeFunc(fn func(path string) (int64, bool)) Option {
+	return func(o *options) error {
+		o.sizeFunc = fn
+		return nil
+	}
+}
+
+// knownSize returns the decompressed size of the named file as returned by the
+// function set with WithSizeFunc.
+func (compressed FileSystem) knownSize(path string) (int64, bool) {
+	if compressed.opts == nil || compressed.opts.sizeFunc == nil {
+		return -1, false
+	}
+	return compressed.opts.sizeFunc(path)
+}
+
+// WithMaxConcurrentDecodes limits the number of files decompressed at once by
+// Open and OpenContext to n. Further calls block until one of the running
+// decodes finished, or, for OpenContext, until its context is done. Files
+// which are stored uncompressed, files opened in streaming mode, which are
+// decompressed while being read, and files served from the cache set with
+// WithCache aren't limited.
+func WithMaxConcurrentDecodes(n int) Option {
+	return func(o *options) error {
+		if n < 1 {
+			return fmt.Errorf("assets: max concurrent decodes must be positive, got %d", n)
+		}
+		o.decodeSlots = make(chan struct{}, n)
+		return nil
+	}
+}
+
+// acquireDecode waits for a slot to decompress a file as limited with
+// WithMaxConcurrentDecodes. The returned function releases the slot.
+func (compressed FileSystem) acquireDecode(ctx context.Context) (func(), error) {
+	if compressed.opts == nil || compressed.opts.decodeSlots == nil {
+		return func() {}, nil
+	}
+	select {
+	case compressed.opts.decodeSlots <- struct{}{}:
+		return func() { <-compressed.opts.decodeSlots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// WithCache makes the FileSystem keep the decompressed content of recently
+// opened files in memory, as configured by opts, see NewCached. Files opened in
+// streaming mode aren't cached.
+func WithCache(opts CacheOptions) Option {
+	return func(o *options) error {
+		if opts.MaxBytes <= 0 {
+			return fmt.Errorf("assets: cache size must be positive, got %d", opts.MaxBytes)
+		}
+		o.cache = newDecodeCache(opts)
+		return nil
+	}
+}
+
+// WithDecodePreference sets the order in which Open tries the codings a file is
+// stored with when decompressing it, e.g. "gzip", "zstd" to prefer the gzip
+// copy of files stored with both. Codings not listed are tried afterwards, in
+// the default order of zstd, br and gzip. Codings without a decoder
+// registered with RegisterDecoder are skipped.
+func WithDecodePreference(encodings ...string) Option {
+	return func(o *options) error {
+		for _, encoding := range encodings {
+			if suffixFor(encoding) == "" {
+				return fmt.Errorf("assets: unknown encoding %q", encoding)
+			}
+		}
+		o.decodeOrder = encodings
+		return nil
+	}
+}
+
+// decodePreference returns all stored codings in the order set with
+// WithDecodePreference.
+func (compressed FileSystem) decodePreference() []string {
+	var order []string
+	if compressed.opts != nil {
+		order = append(order, compressed.opts.decodeOrder...)
+	}
+	for _, se := range compressed.codings() {
+		listed := false
+		for _, encoding := range order {
+			listed = listed || encoding == se.encoding
+		}
+		if !listed {
+			order = append(order, se.encoding)
+		}
+	}
+	return order
+}
+
+// WithHasher sets the hash function used for all content hashing, i.e. for
+// ETags, fingerprints and the hashes returned by Manifest, which ChangedSince
+// compares against. It defaults to SHA-256. The name identifies the algorithm,
+// e.g. "xxhash", and prefixes the hashes in ETags, so that they tell which
+// algorithm was used. For "sha256", "sha384" and "sha512", the function is
+// also used for Integrity.
+func WithHasher(name string, fn func() hash.Hash) Option {
+	return func(o *options) error {
+		if fn == nil {
+			return errors.New("assets: hasher must not be nil")
+		}
+		if !validHasherName(name) {
+			return fmt.Errorf("assets: invalid hasher name %q", name)
+		}
+		o.hasher, o.hasherName = fn, name
+		return nil
+	}
+}
+
+// validHasherName reports whether name is non-empty and only consists of
+// lower case letters, digits and dashes, so that it can be used in ETags.
+func validHasherName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// newHash returns a new hash as configured with WithHasher.
+func (compressed FileSystem) newHash() hash.Hash {
+	if compressed.opts != nil && compressed.opts.hasher != nil {
+		return compressed.opts.hasher()
+	}
+	return sha256.New()
+}
+
+// resolvePath returns the name path refers to within the embed FS, after
+// normalization and alias resolution. Invalid paths, including "", are
+// rejected up front, as probing for suffixes would otherwise turn them into
+// different, possibly valid, names.
+func (compressed FileSystem) resolvePath(op, path string) (string, error) {
+	if compressed.opts != nil && compressed.opts.normalizePaths {
+		path = strings.ReplaceAll(path, `\`, "/")
+	}
+	if !fs.ValidPath(path) {
+		return "", &fs.PathError{Op: op, Path: path, Err: fs.ErrInvalid}
+	}
+	path, _ = compressed.resolveAlias(path)
+	return compressed.resolveFingerprint(compressed.foldCase(path)), nil
+}
+
+// resolve is like resolvePath, for operations reading the content of a file,
+// which are subject to fault injection.
+func (compressed FileSystem) resolve(op, path string) (string, error) {
+	path, err := compressed.resolvePath(op, path)
+	if err != nil {
+		return "", err
+	}
+	if compressed.opts != nil && compressed.opts.faultInjector != nil {
+		if err := compressed.opts.faultInjector(path); err != nil {
+			return "", err
+		}
+	}
+	return path, nil
+}
+
+// resolveAlias returns the target of path if it is an alias, and path
+// otherwise.
+func (compressed FileSystem) resolveAlias(path string) (string, bool) {
+	if compressed.opts == nil {
+		return path, false
+	}
+	if target, ok := compressed.opts.aliases[path]; ok {
+		return target, true
+	}
+	return path, false
+}
@@ -0,0 +1,1184 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha512"
+	"embed"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithName(t *testing.T) {
+	fsys, err := NewWithOptions(brokenEmbedFS, WithName("ui"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = fsys.Open("testdata_broken/corrupt")
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected a *DecodeError, got %v", err)
+	}
+	if decodeErr.FS != "ui" || decodeErr.Path != "testdata_broken/corrupt.gz" {
+		t.Fatalf("error is wrong, got %+v", decodeErr)
+	}
+	if !errors.Is(err, gzip.ErrHeader) {
+		t.Fatalf("expected the error to wrap gzip.ErrHeader, got %v", err)
+	}
+	if expected := "assets: ui: decode testdata_broken/corrupt.gz: gzip: invalid header"; err.Error() != expected {
+		t.Fatalf("error message is wrong, expected %q, got %q", expected, err.Error())
+	}
+
+	_, err = brokenFS.Open("testdata_broken/corrupt")
+	if expected := "assets: decode testdata_broken/corrupt.gz: gzip: invalid header"; err == nil || err.Error() != expected {
+		t.Fatalf("error message is wrong, expected %q, got %v", expected, err)
+	}
+}
+
+func TestWithNameEmpty(t *testing.T) {
+	if _, err := NewWithOptions(EmbedFS, WithName("")); err == nil {
+		t.Fatal("expected an error for an empty name")
+	}
+}
+
+//go:embed testdata/templates/*.gz
+var gzippedFS embed.FS
+
+func TestWithRequireEncoding(t *testing.T) {
+	if _, err := NewWithOptions(gzippedFS, WithRequireEncoding("gzip")); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := NewWithOptions(gzippedFS, WithRequireEncoding("identity"))
+	if err == nil || !strings.Contains(err.Error(), "testdata/templates/greeting.tmpl.gz") {
+		t.Fatalf("expected an error listing the compressed file, got %v", err)
+	}
+
+	_, err = NewWithOptions(EmbedFS, WithRequireEncoding("gzip"))
+	if err == nil {
+		t.Fatal("expected an error for uncompressed files")
+	}
+	for _, name := range []string{"testdata/both", "testdata/uncompressed", "testdata/variants/only.txt.zst"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Fatalf("expected the error to list %s, got %v", name, err)
+		}
+	}
+	if strings.Contains(err.Error(), "testdata/both.gz") {
+		t.Fatalf("expected the error not to list gzipped files, got %v", err)
+	}
+
+	if _, err := NewWithOptions(EmbedFS, WithRequireEncoding("lzma")); err == nil {
+		t.Fatal("expected an error for an unknown encoding")
+	}
+}
+
+func TestWithFaultInjector(t *testing.T) {
+	errInjected := errors.New("injected")
+	fsys, err := NewWithOptions(EmbedFS, WithFaultInjector(func(path string) error {
+		if path == "testdata/compressed" {
+			return errInjected
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fsys.Open("testdata/compressed"); err != errInjected {
+		t.Fatalf("expected the injected error, got %v", err)
+	}
+	if _, err := fsys.ReadString("testdata/compressed"); err != errInjected {
+		t.Fatalf("expected the injected error, got %v", err)
+	}
+	f, err := fsys.Open("testdata/uncompressed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+}
+
+func TestWithAliases(t *testing.T) {
+	fsys, err := NewWithOptions(EmbedFS, WithAliases(map[string]string{
+		"/js/old.js": "testdata/compressed",
+		"plain":      "/testdata/uncompressed",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"js/old.js", "plain"} {
+		s, err := fsys.ReadString(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != "foo\n" {
+			t.Fatalf("content of %s is wrong, got %q", path, s)
+		}
+	}
+	if _, err := testFS.Open("js/old.js"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected aliases to be per FileSystem, got %v", err)
+	}
+
+	handler := fsys.CompressionMiddleware(http.NotFoundHandler())
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/js/old.js", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "foo\n" {
+		t.Fatalf("expected the alias target to be served, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestWithRedirectAliases(t *testing.T) {
+	fsys, err := NewWithOptions(EmbedFS,
+		WithAliases(map[string]string{"js/old.js": "testdata/compressed"}),
+		WithRedirectAliases(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := fsys.CompressionMiddleware(http.NotFoundHandler())
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/js/old.js", nil))
+	if rec.Code != http.StatusMovedPermanently {
+		t.Fatalf("status is wrong, expected %d, got %d", http.StatusMovedPermanently, rec.Code)
+	}
+	if location := rec.Header().Get("Location"); location != "../testdata/compressed" {
+		t.Fatalf("location is wrong, got %q", location)
+	}
+
+	// Open still resolves aliases transparently.
+	if _, err := fsys.ReadString("js/old.js"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWithAliasesInvalid(t *testing.T) {
+	if _, err := NewWithOptions(EmbedFS, WithAliases(map[string]string{"a/../b": "testdata/compressed"})); err == nil {
+		t.Fatal("expected an error for an invalid alias")
+	}
+}
+
+func TestWithPathNormalization(t *testing.T) {
+	fsys, err := NewWithOptions(EmbedFS, WithPathNormalization())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{`testdata\compressed`, `testdata\uncompressed`} {
+		s, err := fsys.ReadString(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != "foo\n" {
+			t.Fatalf("content of %s is wrong, got %q", path, s)
+		}
+		if _, err := testFS.Open(path); err == nil {
+			t.Fatalf("expected %s not to be found without normalization", path)
+		}
+	}
+
+	if _, err := fsys.Open(`\testdata\compressed`); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("expected normalized absolute paths to be invalid, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	fsys.CompressionMiddleware(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, `/testdata%5Ccompressed`, nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "foo\n" {
+		t.Fatalf("expected the normalized path to be served, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestWithHasher(t *testing.T) {
+	fsys, err := NewWithOptions(templatesFS, WithHasher("sha384", sha512.New384))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const name = "testdata/templates/page.tmpl"
+	content, err := templatesFS.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha512.Sum384(content)
+	expected := hex.EncodeToString(sum[:])
+
+	manifest, err := fsys.Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest[name] != expected {
+		t.Fatalf("manifest hash is wrong, expected %s, got %s", expected, manifest[name])
+	}
+
+	changed, err := fsys.ChangedSince(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 0 {
+		t.Fatalf("expected no changes, got %v", changed)
+	}
+	defaultManifest, err := New(templatesFS).Manifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed, err = fsys.ChangedSince(defaultManifest); err != nil || len(changed) != 2 {
+		t.Fatalf("expected hashes of different functions to differ, got %v, %v", changed, err)
+	}
+
+	rec := httptest.NewRecorder()
+	fsys.CompressionMiddleware(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+name, nil))
+	// The ETag tells the algorithm.
+	if etag := rec.Header().Get("ETag"); etag != `"sha384-`+expected+`"` {
+		t.Fatalf("ETag is wrong, expected %q, got %q", `"sha384-`+expected+`"`, etag)
+	}
+	fingerprinted, err := fsys.FingerprintedName(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "testdata/templates/page." + expected[:fingerprintLen] + ".tmpl"; fingerprinted != want {
+		t.Fatalf("fingerprinted name is wrong, expected %s, got %s", want, fingerprinted)
+	}
+	integrity, err := fsys.Integrity(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "sha384-" + base64.StdEncoding.EncodeToString(sum[:]); integrity != want {
+		t.Fatalf("integrity is wrong, expected %s, got %s", want, integrity)
+	}
+
+	// SHA-512 is allowed for SRI as well, other hash functions aren't used
+	// for it.
+	fsys, err = NewWithOptions(templatesFS, WithHasher("sha512", sha512.New))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum512 := sha512.Sum512(content)
+	if integrity, err = fsys.Integrity(name); err != nil || integrity != "sha512-"+base64.StdEncoding.EncodeToString(sum512[:]) {
+		t.Fatalf("expected a SHA-512 integrity, got %q, %v", integrity, err)
+	}
+	fsys, err = NewWithOptions(templatesFS, WithHasher("fnv1a-64", func() hash.Hash { return fnv.New64a() }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if integrity, err = fsys.Integrity(name); err != nil || integrity != "sha384-"+base64.StdEncoding.EncodeToString(sum[:]) {
+		t.Fatalf("expected a SHA-384 integrity, got %q, %v", integrity, err)
+	}
+	if etag, err := fsys.ETag(name); err != nil || !strings.HasPrefix(etag, `"fnv1a-64-`) {
+		t.Fatalf("expected the ETag to tell the algorithm, got %q, %v", etag, err)
+	}
+
+	if _, err := NewWithOptions(EmbedFS, WithHasher("sha256", nil)); err == nil {
+		t.Fatal("expected an error for a nil hasher")
+	}
+	for _, name := range []string{"", "SHA256", `x"y`} {
+		if _, err := NewWithOptions(EmbedFS, WithHasher(name, sha512.New384)); err == nil {
+			t.Fatalf("expected an error for the hasher name %q", name)
+		}
+	}
+}
+
+func TestWithCleanURLs(t *testing.T) {
+	fsys, err := NewWithOptions(EmbedFS, WithCleanURLs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := fsys.CompressionMiddleware(http.NotFoundHandler())
+
+	cases := []struct {
+		path             string
+		accept           string
+		expectedStatus   int
+		expectedEncoding string
+		expectedBody     string
+	}{
+		{
+			path:           "/testdata/site/guide/intro",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "<h1>Intro</h1>\n",
+		},
+		{
+			path:           "/testdata/site/guide/intro.html",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "<h1>Intro</h1>\n",
+		},
+		{
+			path:           "/testdata/site/guide/setup",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "<h1>Setup</h1>\n",
+		},
+		{
+			path:             "/testdata/site/guide/setup/",
+			accept:           "gzip",
+			expectedStatus:   http.StatusOK,
+			expectedEncoding: "gzip",
+		},
+		{
+			path:           "/testdata/site/guide/intro.js",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			path:           "/testdata/site/guide/missing",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			// Paths of existing files are left alone.
+			path:           "/testdata/uncompressed",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "foo\n",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			req.Header.Set("Accept-Encoding", c.accept)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != c.expectedStatus {
+				t.Fatalf("status is wrong, expected %d, got %d", c.expectedStatus, rec.Code)
+			}
+			if encoding := rec.Header().Get("Content-Encoding"); encoding != c.expectedEncoding {
+				t.Fatalf("encoding is wrong, expected %q, got %q", c.expectedEncoding, encoding)
+			}
+			if c.expectedBody != "" && rec.Body.String() != c.expectedBody {
+				t.Fatalf("body is wrong, expected %q, got %q", c.expectedBody, rec.Body.String())
+			}
+			if c.expectedStatus == http.StatusOK && !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/") {
+				t.Fatalf("content type is wrong, got %q", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	testFS.CompressionMiddleware(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/testdata/site/guide/intro", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected clean URLs to be disabled by default, got %d", rec.Code)
+	}
+}
+
+func TestWithPreloadLinks(t *testing.T) {
+	fsys, err := NewWithOptions(EmbedFS, WithPreloadLinks(map[string][]string{
+		"/testdata/site/guide/intro.html": {"/testdata/site/app.js", "testdata/site/style.css"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := fsys.CompressionMiddleware(http.NotFoundHandler())
+
+	for _, accept := range []string{"", "gzip"} {
+		req := httptest.NewRequest(http.MethodGet, "/testdata/site/guide/intro.html", nil)
+		req.Header.Set("Accept-Encoding", accept)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		expected := []string{
+			"</testdata/site/app.js>; rel=preload; as=script",
+			"</testdata/site/style.css>; rel=preload; as=style",
+		}
+		if links := rec.Header().Values("Link"); !reflect.DeepEqual(links, expected) {
+			t.Fatalf("links are wrong, expected %q, got %q", expected, links)
+		}
+	}
+
+	// Files without dependencies don't get any links.
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/testdata/site/app.js", nil))
+	if links := rec.Header().Values("Link"); len(links) != 0 {
+		t.Fatalf("expected no links, got %q", links)
+	}
+}
+
+func TestWithPreloadLinksMissing(t *testing.T) {
+	_, err := NewWithOptions(EmbedFS, WithPreloadLinks(map[string][]string{
+		"testdata/site/guide/intro.html": {"testdata/site/missing.js"},
+	}))
+	if err == nil {
+		t.Fatal("expected an error for a missing dependency")
+	}
+}
+
+func TestPreloadDestination(t *testing.T) {
+	for ctype, expected := range map[string]string{
+		"text/javascript; charset=utf-8": "script",
+		"text/css; charset=utf-8":        "style",
+		"font/woff2":                     "font",
+		"image/png":                      "image",
+		"application/json":               "fetch",
+	} {
+		if actual := preloadDestination(ctype); actual != expected {
+			t.Fatalf("destination of %q is wrong, expected %q, got %q", ctype, expected, actual)
+		}
+	}
+}
+
+func TestWithNoDecompressExtensions(t *testing.T) {
+	stored, err := EmbedFS.ReadFile("testdata/images/pixel.png.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := fs.ReadFile(testFS, "testdata/images/pixel.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dynamic := strings.Repeat("a", 2*minCompressSize)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(dynamic))
+	})
+
+	fsys, err := NewWithOptions(EmbedFS, WithNoDecompressExtensions("PNG"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name             string
+		fsys             FileSystem
+		path             string
+		accept           string
+		expectedEncoding string
+		expectedType     string
+		expectedBody     []byte
+	}{
+		{
+			name:             "stored bytes for client accepting gzip",
+			fsys:             fsys,
+			path:             "/testdata/images/pixel.png",
+			accept:           "gzip",
+			expectedEncoding: "gzip",
+			expectedType:     "image/png",
+			expectedBody:     stored,
+		},
+		{
+			name:         "stored bytes as identity for client not accepting gzip",
+			fsys:         fsys,
+			path:         "/testdata/images/pixel.png",
+			expectedType: "application/gzip",
+			expectedBody: stored,
+		},
+		{
+			name:         "decoded without option",
+			fsys:         testFS,
+			path:         "/testdata/images/pixel.png",
+			expectedType: "image/png",
+			expectedBody: decoded,
+		},
+		{
+			name:         "other extensions are decoded",
+			fsys:         fsys,
+			path:         "/testdata/compressed",
+			expectedBody: []byte("foo\n"),
+		},
+		{
+			name:         "dynamic responses aren't compressed",
+			fsys:         fsys,
+			path:         "/generated.png",
+			accept:       "gzip",
+			expectedBody: []byte(dynamic),
+		},
+		{
+			name:             "other dynamic responses are compressed",
+			fsys:             fsys,
+			path:             "/generated.txt",
+			accept:           "gzip",
+			expectedEncoding: "gzip",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			req.Header.Set("Accept-Encoding", c.accept)
+			rec := httptest.NewRecorder()
+			c.fsys.CompressionMiddleware(next).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status is wrong, expected %d, got %d", http.StatusOK, rec.Code)
+			}
+			if encoding := rec.Header().Get("Content-Encoding"); encoding != c.expectedEncoding {
+				t.Fatalf("encoding is wrong, expected %q, got %q", c.expectedEncoding, encoding)
+			}
+			if ctype := rec.Header().Get("Content-Type"); c.expectedType != "" && ctype != c.expectedType {
+				t.Fatalf("content type is wrong, expected %q, got %q", c.expectedType, ctype)
+			}
+			if c.expectedBody != nil && !bytes.Equal(rec.Body.Bytes(), c.expectedBody) {
+				t.Fatalf("body is wrong, expected %q, got %q", c.expectedBody, rec.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestWithNoDecompressExtensionsEmpty(t *testing.T) {
+	if _, err := NewWithOptions(EmbedFS, WithNoDecompressExtensions(".")); err == nil {
+		t.Fatal("expected an error for an empty extension")
+	}
+}
+
+func TestWithImmutablePattern(t *testing.T) {
+	fsys, err := NewWithOptions(EmbedFS, WithImmutablePattern(regexp.MustCompile(`\.[0-9a-f]{6}\.js$`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := fsys.CompressionMiddleware(http.NotFoundHandler())
+
+	cases := []struct {
+		name           string
+		path           string
+		header         string
+		value          string
+		expectedStatus int
+		expectedCache  string
+	}{
+		{
+			name:           "fingerprinted",
+			path:           "/testdata/site/static/app.3f9a1c.js",
+			expectedStatus: http.StatusOK,
+			expectedCache:  "public, max-age=31536000, immutable",
+		},
+		{
+			name:           "fingerprinted with stale ETag",
+			path:           "/testdata/site/static/app.3f9a1c.js",
+			header:         "If-None-Match",
+			value:          `"stale"`,
+			expectedStatus: http.StatusNotModified,
+			expectedCache:  "public, max-age=31536000, immutable",
+		},
+		{
+			name:           "fingerprinted with modification time",
+			path:           "/testdata/site/static/app.3f9a1c.js",
+			header:         "If-Modified-Since",
+			value:          "Mon, 01 Jan 2024 00:00:00 GMT",
+			expectedStatus: http.StatusNotModified,
+			expectedCache:  "public, max-age=31536000, immutable",
+		},
+		{
+			name:           "not fingerprinted",
+			path:           "/testdata/site/app.js",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "not fingerprinted with stale ETag",
+			path:           "/testdata/site/app.js",
+			header:         "If-None-Match",
+			value:          `"stale"`,
+			expectedStatus: http.StatusOK,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, c.path, nil)
+			if c.header != "" {
+				req.Header.Set(c.header, c.value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != c.expectedStatus {
+				t.Fatalf("status is wrong, expected %d, got %d", c.expectedStatus, rec.Code)
+			}
+			if cache := rec.Header().Get("Cache-Control"); cache != c.expectedCache {
+				t.Fatalf("Cache-Control is wrong, expected %q, got %q", c.expectedCache, cache)
+			}
+			if c.expectedStatus == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Fatalf("expected no body, got %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestWithImmutablePatternNil(t *testing.T) {
+	if _, err := NewWithOptions(EmbedFS, WithImmutablePattern(nil)); err == nil {
+		t.Fatal("expected an error for a nil pattern")
+	}
+}
+
+// chunkWriter records the largest write it got, discarding the data.
+type chunkWriter struct {
+	written  int64
+	maxChunk int
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+	if len(p) > w.maxChunk {
+		w.maxChunk = len(p)
+	}
+	return len(p), nil
+}
+
+func TestWithReadBufferSize(t *testing.T) {
+	for _, c := range []struct {
+		opts     []Option
+		maxChunk int
+	}{
+		{[]Option{WithStreaming()}, defaultReadBufferSize},
+		{[]Option{WithStreaming(), WithReadBufferSize(4096)}, 4096},
+	} {
+		fsys, err := NewWithOptions(EmbedFS, c.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := fsys.Open("testdata/large.bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := &chunkWriter{}
+		n, err := io.Copy(w, f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 16<<20 || w.written != n {
+			t.Fatalf("expected %d bytes to be copied, got %d", 16<<20, n)
+		}
+		if w.maxChunk != c.maxChunk {
+			t.Fatalf("chunk size is wrong, expected %d, got %d", c.maxChunk, w.maxChunk)
+		}
+	}
+
+	if _, err := NewWithOptions(EmbedFS, WithReadBufferSize(0)); err == nil {
+		t.Fatal("expected an error for a zero buffer size")
+	}
+}
+
+func TestWithStreamingThreshold(t *testing.T) {
+	fsys, err := NewWithOptions(EmbedFS, WithStreamingThreshold(1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, streamed := range map[string]bool{
+		"testdata/large.bin":  true,
+		"testdata/compressed": false,
+	} {
+		f, err := fsys.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := f.(*File).reader != nil; got != streamed {
+			t.Errorf("%s: expected streamed to be %t, got %t", path, streamed, got)
+		}
+		stat, err := f.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := io.Copy(io.Discard, f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != stat.Size() {
+			t.Errorf("%s: expected %d bytes, got %d", path, stat.Size(), n)
+		}
+	}
+
+	// A size from the size function takes precedence over the gzip trailer.
+	fsys, err = NewWithOptions(EmbedFS, WithStreamingThreshold(1<<20), WithSizeFunc(func(path string) (int64, bool) {
+		return 1 << 20, path == "testdata/compressed"
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := fsys.Open("testdata/compressed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if f.(*File).reader == nil {
+		t.Error("expected the file to be streamed")
+	}
+
+	if _, err := NewWithOptions(EmbedFS, WithStreamingThreshold(0)); err == nil {
+		t.Fatal("expected an error for a zero threshold")
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	f, err := testFS.Open("testdata/compressed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var buf bytes.Buffer
+	if n, err := f.(io.WriterTo).WriteTo(&buf); err != nil || n != 4 {
+		t.Fatalf("expected 4 bytes to be written, got %d, %v", n, err)
+	}
+	if buf.String() != "foo\n" {
+		t.Fatalf("content is wrong, got %q", buf.String())
+	}
+	// Everything has been consumed.
+	if n, err := f.(io.WriterTo).WriteTo(&buf); err != nil || n != 0 {
+		t.Fatalf("expected nothing to be written, got %d, %v", n, err)
+	}
+}
+
+func TestWithProxyFriendlyHeaders(t *testing.T) {
+	fsys, err := NewWithOptions(EmbedFS, WithProxyFriendlyHeaders())
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Transfer-Encoding", "chunked")
+		if r.URL.Path == "/double" {
+			w.Header().Add("Content-Encoding", "gzip")
+			w.Header().Add("Content-Encoding", "GZIP")
+		}
+		w.Write([]byte(strings.Repeat("a", 2*minCompressSize)))
+	})
+	handler := fsys.CompressionMiddleware(next)
+
+	for _, c := range []struct {
+		path             string
+		accept           string
+		expectedEncoding string
+	}{
+		{"/testdata/compressed", "", ""},
+		{"/testdata/compressed", "gzip", "gzip"},
+		{"/dynamic", "", ""},
+		{"/dynamic", "gzip", "gzip"},
+		{"/double", "gzip", "gzip"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, c.path, nil)
+		req.Header.Set("Accept-Encoding", c.accept)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status is wrong, expected %d, got %d", c.path, http.StatusOK, rec.Code)
+		}
+		if values := rec.Header().Values("Content-Encoding"); c.expectedEncoding != "" && (len(values) != 1 || values[0] != c.expectedEncoding) {
+			t.Fatalf("%s: expected a single Content-Encoding %q, got %q", c.path, c.expectedEncoding, values)
+		} else if c.expectedEncoding == "" && len(values) != 0 {
+			t.Fatalf("%s: expected no Content-Encoding, got %q", c.path, values)
+		}
+		if _, err := http.ParseTime(rec.Header().Get("Date")); err != nil {
+			t.Fatalf("%s: invalid Date: %v", c.path, err)
+		}
+		if te := rec.Header().Values("Transfer-Encoding"); len(te) != 0 {
+			t.Fatalf("%s: expected no Transfer-Encoding, got %q", c.path, te)
+		}
+	}
+}
+
+func TestNormalizeHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set("Date", "Mon, 01 Jan 2024 00:00:00 GMT")
+	h.Add("Content-Encoding", "gzip, br")
+	h.Add("Content-Encoding", "gzip")
+	normalizeHeaders(h)
+	if values := h.Values("Content-Encoding"); len(values) != 1 || values[0] != "gzip, br" {
+		t.Fatalf("Content-Encoding is wrong, got %q", values)
+	}
+	if date := h.Get("Date"); date != "Mon, 01 Jan 2024 00:00:00 GMT" {
+		t.Fatalf("expected Date to be kept, got %q", date)
+	}
+}
+
+func TestWithPreferSmallest(t *testing.T) {
+	fsys, err := NewWithOptions(EmbedFS, WithPreferSmallest())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The zstd variant is stored uncompressed, which makes it larger than
+	// the gzip one, despite zstd being preferred otherwise.
+	for _, c := range []struct {
+		fsys             FileSystem
+		accept           string
+		expectedEncoding string
+	}{
+		{fsys, "zstd, gzip", "gzip"},
+		{fsys, "zstd;q=1, gzip;q=0.1", "gzip"},
+		{fsys, "*", "gzip"},
+		{fsys, "zstd", "zstd"},
+		{fsys, "zstd, gzip;q=0", "zstd"},
+		{fsys, "", ""},
+		{testFS, "zstd, gzip", "zstd"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/testdata/smallest/data.txt", nil)
+		req.Header.Set("Accept-Encoding", c.accept)
+		rec := httptest.NewRecorder()
+		c.fsys.CompressionMiddleware(http.NotFoundHandler()).ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: status is wrong, expected %d, got %d", c.accept, http.StatusOK, rec.Code)
+		}
+		if encoding := rec.Header().Get("Content-Encoding"); encoding != c.expectedEncoding {
+			t.Fatalf("%q: encoding is wrong, expected %q, got %q", c.accept, c.expectedEncoding, encoding)
+		}
+	}
+}
+
+var (
+	//go:embed testdata/site
+	siteFS embed.FS
+	//go:embed testdata/casefold
+	casefoldFS embed.FS
+)
+
+func TestWithCaseInsensitive(t *testing.T) {
+	fsys, err := NewWithOptions(siteFS, WithCaseInsensitive())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"testdata/site/app.js", "TestData/Site/APP.js", "testdata/SITE/style.CSS"} {
+		f, err := fsys.Open(name)
+		if err != nil {
+			t.Fatalf("unexpected error opening %s: %v", name, err)
+		}
+		stat, err := f.Stat()
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := strings.ToLower(path.Base(name)); stat.Name() != expected {
+			t.Fatalf("name is wrong, expected %q, got %q", expected, stat.Name())
+		}
+	}
+	if _, err := fs.Stat(fsys, "TESTDATA/SITE/GUIDE"); err != nil {
+		t.Fatalf("unexpected error for a directory: %v", err)
+	}
+	if _, err := fsys.Open("testdata/site/missing.js"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected fs.ErrNotExist, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	fsys.CompressionMiddleware(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/testdata/site/Style.css", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "body { margin: 0; }\n" {
+		t.Fatalf("expected the canonical file to be served, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	// Without the option, names are case sensitive.
+	if _, err := New(siteFS).Open("testdata/site/APP.js"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected fs.ErrNotExist, got %v", err)
+	}
+}
+
+func TestWithCaseInsensitiveCollision(t *testing.T) {
+	_, err := NewWithOptions(casefoldFS, WithCaseInsensitive())
+	if err == nil || !strings.Contains(err.Error(), "README.txt") || !strings.Contains(err.Error(), "readme.txt") {
+		t.Fatalf("expected an error naming both files, got %v", err)
+	}
+}
+
+func TestWithSecurityHeaders(t *testing.T) {
+	fsys, err := NewWithOptions(EmbedFS, WithSecurityHeaders(map[string]string{
+		"content-security-policy": "default-src 'self'",
+		"X-Frame-Options":         "DENY",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstream := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Frame-Options", "SAMEORIGIN")
+			next.ServeHTTP(w, r)
+		})
+	}
+	handler := upstream(fsys.CompressionMiddleware(http.NotFoundHandler()))
+
+	for _, accept := range []string{"", "gzip"} {
+		req := httptest.NewRequest(http.MethodGet, "/testdata/compressed", nil)
+		req.Header.Set("Accept-Encoding", accept)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status is wrong, expected %d, got %d", http.StatusOK, rec.Code)
+		}
+		for k, expected := range map[string]string{
+			"X-Content-Type-Options":  "nosniff",
+			"Content-Security-Policy": "default-src 'self'",
+			// Set upstream.
+			"X-Frame-Options": "SAMEORIGIN",
+		} {
+			if actual := rec.Header().Values(k); len(actual) != 1 || actual[0] != expected {
+				t.Fatalf("%s is wrong, expected %q, got %q", k, expected, actual)
+			}
+		}
+	}
+
+	// Responses to other methods don't carry them.
+	for _, method := range []string{http.MethodOptions, http.MethodPost} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, "/testdata/compressed", nil))
+		if v := rec.Header().Get("Content-Security-Policy"); v != "" {
+			t.Fatalf("expected no Content-Security-Policy for %s, got %q", method, v)
+		}
+	}
+
+	// The default can be omitted.
+	fsys, err = NewWithOptions(EmbedFS, WithSecurityHeaders(map[string]string{"X-Content-Type-Options": ""}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	fsys.CompressionMiddleware(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/testdata/compressed", nil))
+	if v := rec.Header().Get("X-Content-Type-Options"); v != "" {
+		t.Fatalf("expected no X-Content-Type-Options, got %q", v)
+	}
+}
+
+func TestWithResponseHook(t *testing.T) {
+	var calls []string
+	hook := func(w http.ResponseWriter, r *http.Request, path string, info fs.FileInfo) {
+		calls = append(calls, path+" "+info.Name())
+		w.Header().Set("X-Audit", path)
+		// These are reverted.
+		w.Header().Set("Content-Encoding", "br")
+		w.Header().Set("Content-Length", "1")
+	}
+	fsys, err := NewWithOptions(EmbedFS, WithResponseHook(hook))
+	if err != nil {
+		t.Fatal(err)
+	}
+	streaming, err := NewWithOptions(EmbedFS, WithResponseHook(hook), WithStreaming())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		fsys             FileSystem
+		method           string
+		path             string
+		accept           string
+		expectedStatus   int
+		expectedCall     string
+		expectedEncoding string
+		expectedBody     string
+	}{
+		{fsys, http.MethodGet, "/testdata/compressed", "", http.StatusOK, "testdata/compressed compressed", "", "foo\n"},
+		{fsys, http.MethodGet, "/testdata/compressed", "gzip", http.StatusOK, "testdata/compressed compressed.gz", "gzip", ""},
+		{streaming, http.MethodGet, "/testdata/compressed", "", http.StatusOK, "testdata/compressed compressed", "", "foo\n"},
+		{fsys, http.MethodGet, "/testdata/missing", "", http.StatusNotFound, "", "", ""},
+		{fsys, http.MethodPost, "/testdata/compressed", "", http.StatusMethodNotAllowed, "", "", ""},
+	} {
+		calls = nil
+		req := httptest.NewRequest(c.method, c.path, nil)
+		req.Header.Set("Accept-Encoding", c.accept)
+		rec := httptest.NewRecorder()
+		c.fsys.CompressionMiddleware(http.NotFoundHandler()).ServeHTTP(rec, req)
+
+		if rec.Code != c.expectedStatus {
+			t.Fatalf("%s %s: status is wrong, expected %d, got %d", c.method, c.path, c.expectedStatus, rec.Code)
+		}
+		if c.expectedCall == "" {
+			if len(calls) != 0 {
+				t.Fatalf("%s %s: expected no hook calls, got %q", c.method, c.path, calls)
+			}
+			continue
+		}
+		if len(calls) != 1 || calls[0] != c.expectedCall {
+			t.Fatalf("%s %s: expected hook call %q, got %q", c.method, c.path, c.expectedCall, calls)
+		}
+		if audit := rec.Header().Get("X-Audit"); audit != "testdata/compressed" {
+			t.Fatalf("expected the header set by the hook, got %q", audit)
+		}
+		if encoding := rec.Header().Get("Content-Encoding"); encoding != c.expectedEncoding {
+			t.Fatalf("encoding is wrong, expected %q, got %q", c.expectedEncoding, encoding)
+		}
+		if length := rec.Header().Get("Content-Length"); length == "1" {
+			t.Fatal("expected the Content-Length set by the hook to be reverted")
+		}
+		if c.expectedBody != "" && rec.Body.String() != c.expectedBody {
+			t.Fatalf("body is wrong, expected %q, got %q", c.expectedBody, rec.Body.String())
+		}
+	}
+}
+
+func TestWithVariantSelector(t *testing.T) {
+	fast, err := EmbedFS.ReadFile("testdata/levels/app.js.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	best, err := EmbedFS.ReadFile("testdata/levels/app.js.max.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var candidates []string
+	saveData, err := NewWithOptions(EmbedFS, WithVariantSelector(func(r *http.Request, c []string) string {
+		candidates = c
+		if r.Header.Get("Save-Data") == "on" {
+			return "testdata/levels/app.js.max.gz"
+		}
+		return c[0]
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	smallest, err := NewWithOptions(EmbedFS, WithVariantSelector(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		name     string
+		fsys     FileSystem
+		saveData bool
+		expected []byte
+	}{
+		{"selector", saveData, false, fast},
+		{"selector with hint", saveData, true, best},
+		{"smallest", smallest, false, best},
+		{"without selector", testFS, true, fast},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/testdata/levels/app.js", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		if c.saveData {
+			req.Header.Set("Save-Data", "on")
+		}
+		rec := httptest.NewRecorder()
+		c.fsys.CompressionMiddleware(http.NotFoundHandler()).ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status is wrong, expected %d, got %d", c.name, http.StatusOK, rec.Code)
+		}
+		if !bytes.Equal(rec.Body.Bytes(), c.expected) {
+			t.Fatalf("%s: wrong variant served", c.name)
+		}
+	}
+	if expected := []string{"testdata/levels/app.js.gz", "testdata/levels/app.js.max.gz"}; !reflect.DeepEqual(candidates, expected) {
+		t.Fatalf("candidates are wrong, expected %q, got %q", expected, candidates)
+	}
+}
+
+func TestWithSizeFunc(t *testing.T) {
+	var consulted []string
+	sizes := map[string]int64{
+		"testdata/compressed":        42,
+		"testdata/variants/only.txt": 16,
+	}
+	fsys, err := NewWithOptions(EmbedFS, WithStreaming(), WithSizeFunc(func(path string) (int64, bool) {
+		consulted = append(consulted, path)
+		size, ok := sizes[path]
+		return size, ok
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expected := range map[string]int64{
+		"testdata/compressed": 42,
+		// Not known by the function, taken from the gzip trailer.
+		"testdata/variants/data.txt": 16,
+	} {
+		consulted = nil
+		stat, err := fs.Stat(fsys, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stat.Size() != expected {
+			t.Fatalf("size of %s is wrong, expected %d, got %d", path, expected, stat.Size())
+		}
+		if len(consulted) != 1 || consulted[0] != path {
+			t.Fatalf("expected the size function to be consulted for %s, got %q", path, consulted)
+		}
+	}
+
+	r, _, size, _, err := fsys.OpenRawWithSize("testdata/variants/only.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if size != 16 {
+		t.Fatalf("decompressed size is wrong, expected 16, got %d", size)
+	}
+	if size, err := fsys.ContentLength("testdata/variants/only.txt", "identity"); err != nil || size != 16 {
+		t.Fatalf("content length is wrong, expected 16, got %d, %v", size, err)
+	}
+	if _, err := testFS.ContentLength("testdata/variants/only.txt", "identity"); err == nil {
+		t.Fatal("expected an error without the size function")
+	}
+}
+
+func TestWithMaxConcurrentDecodes(t *testing.T) {
+	fsys, err := NewWithOptions(siteFS, WithMaxConcurrentDecodes(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mtx            sync.Mutex
+		active, maxAct int
+		wg             sync.WaitGroup
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := fsys.acquireDecode(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mtx.Lock()
+			active++
+			if active > maxAct {
+				maxAct = active
+			}
+			mtx.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mtx.Lock()
+			active--
+			mtx.Unlock()
+			release()
+		}()
+	}
+	wg.Wait()
+	if maxAct != 2 {
+		t.Fatalf("expected at most 2 concurrent decodes, got %d", maxAct)
+	}
+
+	// Occupy all slots.
+	for i := 0; i < 2; i++ {
+		release, err := fsys.acquireDecode(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer release()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := fsys.OpenContext(ctx, "testdata/site/style.css"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the context error, got %v", err)
+	}
+	// Uncompressed files don't need a slot.
+	f, err := fsys.OpenContext(ctx, "testdata/site/app.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+}
+
+func TestWithMaxConcurrentDecodesInvalid(t *testing.T) {
+	if _, err := NewWithOptions(siteFS, WithMaxConcurrentDecodes(0)); err == nil {
+		t.Fatal("expected an error for a limit of 0")
+	}
+}
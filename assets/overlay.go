@@ -0,0 +1,76 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"sort"
+)
+
+// NewOverlay returns an fs.FS serving files from the local directory devDir if
+// they exist there, and from embedded otherwise. This allows changing assets
+// during development without rebuilding. Directories list the entries of both,
+// preferring those in devDir. If devDir is empty, embedded is returned as-is,
+// so that the overlay can be disabled in production, e.g. by taking devDir
+// from a flag or environment variable that isn't set there.
+func NewOverlay(devDir string, embedded FileSystem) fs.FS {
+	if devDir == "" {
+		return embedded
+	}
+	return overlayFS{disk: os.DirFS(devDir), embedded: embedded}
+}
+
+type overlayFS struct {
+	disk     fs.FS
+	embedded FileSystem
+}
+
+// Open implements the fs.FS interface.
+func (o overlayFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	f, err := o.disk.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o.embedded.Open(name)
+	}
+	return f, err
+}
+
+// ReadDir implements the fs.ReadDirFS interface.
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	diskEntries, diskErr := fs.ReadDir(o.disk, name)
+	if diskErr != nil && !errors.Is(diskErr, fs.ErrNotExist) {
+		return nil, diskErr
+	}
+	embeddedEntries, embeddedErr := o.embedded.ReadDir(name)
+	if embeddedErr != nil && (diskErr != nil || !errors.Is(embeddedErr, fs.ErrNotExist)) {
+		return nil, embeddedErr
+	}
+
+	seen := make(map[string]bool, len(diskEntries))
+	entries := diskEntries
+	for _, e := range diskEntries {
+		seen[e.Name()] = true
+	}
+	for _, e := range embeddedEntries {
+		if !seen[e.Name()] {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
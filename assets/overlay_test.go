@@ -0,0 +1,80 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNewOverlay(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "testdata", "site"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"app.js": "console.log(\"dev\");\n",
+		"new.js": "console.log(\"new\");\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, "testdata", "site", name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	embedded := New(siteFS)
+	fsys := NewOverlay(dir, embedded)
+
+	for name, expected := range map[string]string{
+		"testdata/site/app.js":    "console.log(\"dev\");\n",
+		"testdata/site/new.js":    "console.log(\"new\");\n",
+		"testdata/site/style.css": "body { margin: 0; }\n",
+	} {
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != expected {
+			t.Fatalf("content of %s is wrong, expected %q, got %q", name, expected, content)
+		}
+	}
+
+	entries, err := fs.ReadDir(fsys, "testdata/site")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	expected := []string{"app.js", "guide", "index.html", "new.js", "static", "style.css"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("entries are wrong, expected %q, got %q", expected, names)
+	}
+
+	if _, err := fs.ReadDir(fsys, "testdata/missing"); err == nil {
+		t.Fatal("expected an error for a missing directory")
+	}
+	if _, err := fsys.Open("../secret"); err == nil {
+		t.Fatal("expected an error for an invalid path")
+	}
+}
+
+func TestNewOverlayDisabled(t *testing.T) {
+	embedded := New(siteFS)
+	if _, ok := NewOverlay("", embedded).(FileSystem); !ok {
+		t.Fatal("expected the embedded FileSystem without a directory")
+	}
+}
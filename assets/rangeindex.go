@@ -0,0 +1,168 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// rangeMember describes a gzip member of a file, as listed in the index set
+// with WithCompressedRangeIndex.
+type rangeMember struct {
+	// The offset of the member within the compressed file.
+	Offset int64 `json:"offset"`
+	// The size of the decompressed content of the member.
+	Size int64 `json:"size"`
+}
+
+// WithCompressedRangeIndex makes CompressionMiddleware answer range requests
+// from clients accepting gzip for files stored as several gzip members by
+// sending the members covering the requested range as-is. Ranges are given in
+// terms of the decompressed content, and widened to whole members, so the
+// response may contain more than requested. Its Content-Range is given in
+// terms of the compressed file, as that's what is sent. Only single ranges are
+// supported, others are served as usual.
+//
+// The member boundaries are read from the JSON file at path in fsys, mapping
+// names to the offsets of all members within the compressed file, and the
+// decompressed sizes of their content:
+//
+//	{"app.js": [{"offset": 0, "size": 900}, {"offset": 47, "size": 950}]}
+func WithCompressedRangeIndex(fsys fs.FS, path string) Option {
+	return func(o *options) error {
+		content, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return fmt.Errorf("assets: reading range index: %w", err)
+		}
+		var index map[string][]rangeMember
+		if err := json.Unmarshal(content, &index); err != nil {
+			return fmt.Errorf("assets: parsing range index %s: %w", path, err)
+		}
+		for name, members := range index {
+			for i, m := range members {
+				if i == 0 && m.Offset != 0 || i > 0 && m.Offset <= members[i-1].Offset || m.Size < 0 {
+					return fmt.Errorf("assets: invalid member %d of %s in range index %s", i, name, path)
+				}
+			}
+		}
+		o.rangeIndex = index
+		return nil
+	}
+}
+
+// serveMemberRange writes the gzip members of name covering the requested
+// range to w, as set with WithCompressedRangeIndex, read from the stored file
+// of the given size.
+// It returns false if nothing was written, as the request can't be answered
+// that way.
+func (compressed FileSystem) serveMemberRange(w http.ResponseWriter, r *http.Request, name string, stored io.ReadSeeker, storedSize int64, etag string) bool {
+	if compressed.opts == nil {
+		return false
+	}
+	members, ok := compressed.opts.rangeIndex[name]
+	if !ok || len(members) == 0 {
+		return false
+	}
+	if ifRange := r.Header.Get("If-Range"); ifRange != "" && ifRange != etag {
+		return false
+	}
+	var size int64
+	for _, m := range members {
+		size += m.Size
+	}
+	start, end, ok := parseRange(r.Header.Get("Range"), size)
+	if !ok {
+		return false
+	}
+
+	// Find the members containing the first and last requested byte.
+	first, last := -1, -1
+	var offset int64
+	for i, m := range members {
+		if first < 0 && start < offset+m.Size {
+			first = i
+		}
+		if end < offset+m.Size {
+			last = i
+			break
+		}
+		offset += m.Size
+	}
+	if first < 0 || last < 0 {
+		return false
+	}
+	from, to := members[first].Offset, storedSize
+	if last+1 < len(members) {
+		to = members[last+1].Offset
+	}
+	if from >= to || to > storedSize {
+		return false
+	}
+	if _, err := stored.Seek(from, io.SeekStart); err != nil {
+		return false
+	}
+
+	h := w.Header()
+	h.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", from, to-1, storedSize))
+	h.Set("Content-Length", strconv.FormatInt(to-from, 10))
+	w.WriteHeader(http.StatusPartialContent)
+	if r.Method != http.MethodHead {
+		io.CopyN(w, stored, to-from)
+	}
+	return true
+}
+
+// parseRange returns the first and last byte of the single range in the Range
+// header value s, for content of the given size.
+func parseRange(s string, size int64) (int64, int64, bool) {
+	spec, ok := strings.CutPrefix(s, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, 0, false
+	}
+	startStr, endStr, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, 0, false
+	}
+	if startStr == "" {
+		// A suffix range of the last n bytes.
+		n, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, false
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, true
+	}
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false
+	}
+	end := size - 1
+	if endStr != "" {
+		if end, err = strconv.ParseInt(endStr, 10, 64); err != nil || end < start {
+			return 0, 0, false
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return start, end, true
+}
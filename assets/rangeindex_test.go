@@ -0,0 +1,136 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWithCompressedRangeIndex(t *testing.T) {
+	fsys, err := NewWithOptions(EmbedFS, WithCompressedRangeIndex(EmbedFS, "testdata/ranges/index.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := EmbedFS.ReadFile("testdata/ranges/log.txt.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	members := []string{
+		strings.Repeat("first member line\n", 50),
+		strings.Repeat("second member line\n", 50),
+		strings.Repeat("third member line\n", 50),
+	}
+
+	for _, c := range []struct {
+		fsys                 FileSystem
+		rangeHeader          string
+		accept               string
+		expectedContentRange string
+		expectedContent      string
+	}{
+		{fsys, "bytes=0-10", "gzip", "bytes 0-46/142", members[0]},
+		{fsys, "bytes=900-1000", "gzip", "bytes 47-94/142", members[1]},
+		{fsys, "bytes=899-900", "gzip", "bytes 0-94/142", members[0] + members[1]},
+		{fsys, "bytes=1900-", "gzip", "bytes 95-141/142", members[2]},
+		{fsys, "bytes=-10", "gzip", "bytes 95-141/142", members[2]},
+		// Ranges of the decompressed content are served as usual.
+		{fsys, "bytes=0-4", "", "bytes 0-4/2750", "first"},
+		// Without the index, ranges of the compressed file are served.
+		{testFS, "bytes=0-46", "gzip", "bytes 0-46/142", members[0]},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/testdata/ranges/log.txt", nil)
+		req.Header.Set("Range", c.rangeHeader)
+		req.Header.Set("Accept-Encoding", c.accept)
+		rec := httptest.NewRecorder()
+		c.fsys.CompressionMiddleware(http.NotFoundHandler()).ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusPartialContent {
+			t.Fatalf("%s: status is wrong, expected %d, got %d", c.rangeHeader, http.StatusPartialContent, rec.Code)
+		}
+		if contentRange := rec.Header().Get("Content-Range"); contentRange != c.expectedContentRange {
+			t.Fatalf("%s: Content-Range is wrong, expected %q, got %q", c.rangeHeader, c.expectedContentRange, contentRange)
+		}
+		content := rec.Body.String()
+		if c.accept == "gzip" {
+			if !bytes.Contains(stored, rec.Body.Bytes()) {
+				t.Fatalf("%s: expected a part of the stored file", c.rangeHeader)
+			}
+			gr, err := gzip.NewReader(rec.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := io.ReadAll(gr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			content = string(decoded)
+		}
+		if content != c.expectedContent {
+			t.Fatalf("%s: content is wrong, expected %q, got %q", c.rangeHeader, c.expectedContent, content)
+		}
+	}
+
+	// Multiple ranges are served as usual.
+	req := httptest.NewRequest(http.MethodGet, "/testdata/ranges/log.txt", nil)
+	req.Header.Set("Range", "bytes=0-1,5-6")
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	fsys.CompressionMiddleware(http.NotFoundHandler()).ServeHTTP(rec, req)
+	if ctype := rec.Header().Get("Content-Type"); !strings.HasPrefix(ctype, "multipart/byteranges") {
+		t.Fatalf("expected a multipart response, got %q", ctype)
+	}
+}
+
+func TestWithCompressedRangeIndexInvalid(t *testing.T) {
+	indexFS := fstest.MapFS{
+		"invalid.json":   {Data: []byte(`{"a": [`)},
+		"unordered.json": {Data: []byte(`{"a": [{"offset": 0, "size": 1}, {"offset": 0, "size": 1}]}`)},
+	}
+	for _, path := range []string{"missing.json", "invalid.json", "unordered.json"} {
+		if _, err := NewWithOptions(EmbedFS, WithCompressedRangeIndex(indexFS, path)); err == nil {
+			t.Fatalf("expected an error for %s", path)
+		}
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	for _, c := range []struct {
+		header     string
+		start, end int64
+		ok         bool
+	}{
+		{"bytes=0-9", 0, 9, true},
+		{"bytes=5-", 5, 99, true},
+		{"bytes=-10", 90, 99, true},
+		{"bytes=-200", 0, 99, true},
+		{"bytes=90-200", 90, 99, true},
+		{"bytes=100-", 0, 0, false},
+		{"bytes=9-5", 0, 0, false},
+		{"bytes=0-1,3-4", 0, 0, false},
+		{"items=0-1", 0, 0, false},
+		{"bytes=a-b", 0, 0, false},
+	} {
+		start, end, ok := parseRange(c.header, 100)
+		if ok != c.ok || ok && (start != c.start || end != c.end) {
+			t.Fatalf("%q: expected %d-%d %t, got %d-%d %t", c.header, c.start, c.end, c.ok, start, end, ok)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// OpenRawWithSize opens the stored bytes of the named file without decoding
+// them. The stored variant is picked in the same order of preference as used
+// for HTTP responses, falling back to an uncompressed file. Along with the
+// reader, the stored (compressed) size, the decompressed size and the content
+// coding of the variant are returned, with "identity" for uncompressed files.
+//
+// The decompressed size of gzip files is taken from their trailer, which only
+// holds it modulo 2^32 and only for the last member of multi-member files.
+// Sizes that can't be determined without decoding, as for zstd, are -1, unless
+// provided by the function set with WithSizeFunc.
+func (compressed FileSystem) OpenRawWithSize(path string) (r io.ReadCloser, compressedSize, decompressedSize int64, encoding string, err error) {
+	f, encoding, err := compressed.openRaw(path)
+	if err != nil {
+		return nil, -1, -1, "", err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, -1, -1, "", err
+	}
+
+	compressedSize = stat.Size()
+	size, known := compressed.knownSize(path)
+	switch {
+	case encoding == "identity":
+		decompressedSize = compressedSize
+	case known:
+		decompressedSize = size
+	case encoding == "gzip":
+		if decompressedSize, err = gzipSize(f, compressedSize); err != nil {
+			f.Close()
+			return nil, -1, -1, "", compressed.decodeError(path+gzipSuffix, err)
+		}
+	default:
+		decompressedSize = -1
+	}
+	return f, compressedSize, decompressedSize, encoding, nil
+}
+
+// ContentLength returns the length of the body of a response with the named
+// file in the given content coding, without reading the file. For stored
+// codings like "gzip" and "zstd", this is the size of the stored file. For
+// "identity", it is the decompressed size, read from the gzip trailer for
+// gzipped files, with the limitations documented for OpenRawWithSize. An error
+// wrapping fs.ErrNotExist is returned if the file isn't available in the
+// coding, or its decompressed size can't be determined cheaply.
+func (compressed FileSystem) ContentLength(path, encoding string) (int64, error) {
+	path, err := compressed.resolvePath("contentlength", path)
+	if err != nil {
+		return -1, err
+	}
+
+	if encoding == "identity" {
+		if stat, err := fs.Stat(compressed.embed, path); err == nil && stat.Mode().IsRegular() {
+			return stat.Size(), nil
+		}
+		if size, ok := compressed.knownSize(path); ok && compressed.isFile(path) {
+			return size, nil
+		}
+		if f, err := compressed.embed.Open(path + gzipSuffix); err == nil {
+			defer f.Close()
+			stat, err := f.Stat()
+			if err != nil {
+				return -1, err
+			}
+			size, err := gzipSize(f, stat.Size())
+			if err != nil {
+				return -1, compressed.decodeError(path+gzipSuffix, err)
+			}
+			if size >= 0 {
+				return size, nil
+			}
+		}
+	} else if suffix := suffixFor(encoding); suffix != "" {
+		if stat, err := fs.Stat(compressed.embed, path+suffix); err == nil && stat.Mode().IsRegular() {
+			return stat.Size(), nil
+		}
+	}
+	return -1, fmt.Errorf("assets: %s is not available with encoding %s: %w", path, encoding, fs.ErrNotExist)
+}
+
+// openRaw opens the preferred stored variant of the named file, returning it
+// along with its content coding.
+func (compressed FileSystem) openRaw(path string) (fs.File, string, error) {
+	path, err := compressed.resolvePath("open", path)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, encoding := range compressed.storedVariants(path) {
+		if f, err := compressed.embed.Open(path + suffixFor(encoding)); err == nil {
+			return f, encoding, nil
+		}
+	}
+	if compressed.isStored(path) {
+		f, err := compressed.embed.Open(path)
+		return f, "identity", err
+	}
+	return nil, "", &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+}
+
+// gzipSize returns the decompressed size recorded in the ISIZE trailer field
+// of the gzip file f of the given stored size, or -1 if f doesn't support
+// reading at an offset.
+func gzipSize(f fs.File, size int64) (int64, error) {
+	ra, ok := f.(io.ReaderAt)
+	if !ok {
+		return -1, nil
+	}
+	if size < 18 {
+		// Shorter than the smallest possible gzip header and trailer.
+		return -1, io.ErrUnexpectedEOF
+	}
+	var isize [4]byte
+	if _, err := ra.ReadAt(isize[:], size-4); err != nil {
+		return -1, err
+	}
+	return int64(binary.LittleEndian.Uint32(isize[:])), nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+)
+
+func TestOpenRawWithSize(t *testing.T) {
+	cases := []struct {
+		path                     string
+		stored                   string
+		expectedCompressedSize   int64
+		expectedDecompressedSize int64
+		expectedEncoding         string
+	}{
+		{
+			path:                     "testdata/compressed",
+			stored:                   "testdata/compressed.gz",
+			expectedCompressedSize:   35,
+			expectedDecompressedSize: 4,
+			expectedEncoding:         "gzip",
+		},
+		{
+			path:                     "testdata/uncompressed",
+			stored:                   "testdata/uncompressed",
+			expectedCompressedSize:   4,
+			expectedDecompressedSize: 4,
+			expectedEncoding:         "identity",
+		},
+		{
+			path:                     "testdata/variants/data.txt",
+			stored:                   "testdata/variants/data.txt.zst",
+			expectedCompressedSize:   29,
+			expectedDecompressedSize: -1,
+			expectedEncoding:         "zstd",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			r, compressedSize, decompressedSize, encoding, err := testFS.OpenRawWithSize(c.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			if compressedSize != c.expectedCompressedSize {
+				t.Fatalf("compressed size is wrong, expected %d, got %d", c.expectedCompressedSize, compressedSize)
+			}
+			if decompressedSize != c.expectedDecompressedSize {
+				t.Fatalf("decompressed size is wrong, expected %d, got %d", c.expectedDecompressedSize, decompressedSize)
+			}
+			if encoding != c.expectedEncoding {
+				t.Fatalf("encoding is wrong, expected %q, got %q", c.expectedEncoding, encoding)
+			}
+
+			content, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stored, err := EmbedFS.ReadFile(c.stored)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(content, stored) {
+				t.Fatal("expected the stored bytes to be returned")
+			}
+		})
+	}
+}
+
+func TestOpenRawWithSizeMissing(t *testing.T) {
+	if _, _, _, _, err := testFS.OpenRawWithSize("testdata/missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected fs.ErrNotExist, got %v", err)
+	}
+}
+
+func TestContentLength(t *testing.T) {
+	cases := []struct {
+		path     string
+		encoding string
+		expected int64
+	}{
+		{path: "testdata/compressed", encoding: "gzip", expected: 35},
+		{path: "testdata/compressed", encoding: "identity", expected: 4},
+		{path: "testdata/uncompressed", encoding: "identity", expected: 4},
+		{path: "testdata/both", encoding: "identity", expected: 4},
+		{path: "testdata/both", encoding: "gzip", expected: 29},
+		{path: "testdata/variants/data.txt", encoding: "zstd", expected: 29},
+		{path: "testdata/variants/data.txt", encoding: "gzip", expected: 36},
+		{path: "testdata/variants/data.txt", encoding: "identity", expected: 16},
+	}
+	for _, c := range cases {
+		size, err := testFS.ContentLength(c.path, c.encoding)
+		if err != nil {
+			t.Fatalf("%s with %s: %v", c.path, c.encoding, err)
+		}
+		if size != c.expected {
+			t.Fatalf("%s with %s: length is wrong, expected %d, got %d", c.path, c.encoding, c.expected, size)
+		}
+	}
+}
+
+func TestContentLengthUnavailable(t *testing.T) {
+	cases := []struct {
+		path     string
+		encoding string
+	}{
+		{path: "testdata/uncompressed", encoding: "gzip"},
+		{path: "testdata/compressed", encoding: "zstd"},
+		{path: "testdata/compressed", encoding: "br"},
+		{path: "testdata/variants/only.txt", encoding: "identity"},
+		{path: "testdata/missing", encoding: "identity"},
+		{path: "testdata", encoding: "identity"},
+	}
+	for _, c := range cases {
+		if _, err := testFS.ContentLength(c.path, c.encoding); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("%s with %s: expected fs.ErrNotExist, got %v", c.path, c.encoding, err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ValidateReferences returns the sorted names of all files referenced by the
+// src and href attributes of the HTML file htmlPath that don't exist, e.g. to
+// check at startup that a built single page application is complete.
+// References to other hosts, and those with a scheme like "data:", are
+// ignored. Relative references are resolved against the directory of
+// htmlPath, absolute ones against the root of the FileSystem.
+func (compressed FileSystem) ValidateReferences(htmlPath string) ([]string, error) {
+	content, err := compressed.ReadString(htmlPath)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+
+	missing := map[string]bool{}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			for _, attr := range n.Attr {
+				if attr.Namespace != "" || attr.Key != "src" && attr.Key != "href" {
+					continue
+				}
+				name, ok := localReference(htmlPath, attr.Val)
+				if !ok {
+					continue
+				}
+				if resolved, err := compressed.resolvePath("open", name); err != nil || !compressed.isFile(resolved) {
+					missing[name] = true
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	names := make([]string, 0, len(missing))
+	for name := range missing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// localReference returns the name of the file the reference ref in the HTML
+// file htmlPath points to, and false if it doesn't point to a file of the
+// same FileSystem. References to directories, like "/" or "guide/", point to
+// their index.html, as served by http.FileServer.
+func localReference(htmlPath, ref string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Opaque != "" || u.Path == "" {
+		return "", false
+	}
+	var name string
+	if strings.HasPrefix(u.Path, "/") {
+		name = strings.TrimPrefix(path.Clean(u.Path), "/")
+	} else {
+		name = path.Join(path.Dir(htmlPath), u.Path)
+	}
+	if base := path.Base(u.Path); strings.HasSuffix(u.Path, "/") || base == "." || base == ".." {
+		name = path.Join(name, "index.html")
+	}
+	return name, true
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateReferences(t *testing.T) {
+	missing, err := testFS.ValidateReferences("testdata/site/index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		// Linked as "guide/", without an index.html.
+		"testdata/site/guide/index.html",
+		"testdata/site/images/favicon.ico",
+		"testdata/site/images/logo dark.png",
+		"testdata/site/missing.js",
+	}
+	if !reflect.DeepEqual(missing, expected) {
+		t.Fatalf("missing references are wrong, expected %q, got %q", expected, missing)
+	}
+
+	if _, err := testFS.ValidateReferences("testdata/site/missing.html"); err == nil {
+		t.Fatal("expected an error for a missing HTML file")
+	}
+}
+
+func TestLocalReference(t *testing.T) {
+	for ref, expected := range map[string]string{
+		"app.js":               "static/app.js",
+		"./css/style.css":      "static/css/style.css",
+		"../index.html":        "index.html",
+		"/img/logo.png":        "img/logo.png",
+		"/img/../logo.png":     "logo.png",
+		"page.html?x=1#anchor": "static/page.html",
+		"/":                    "index.html",
+		"guide/":               "static/guide/index.html",
+		"./":                   "static/index.html",
+		"..":                   "index.html",
+		"/docs/?x=1":           "docs/index.html",
+		"https://example.com/": "",
+		"//example.com/a.js":   "",
+		"data:text/plain,hi":   "",
+		"mailto:a@example.com": "",
+		"#top":                 "",
+		"?query":               "",
+	} {
+		name, ok := localReference("static/index.html", ref)
+		if ok != (expected != "") || name != expected {
+			t.Fatalf("reference %q resolved wrong, expected %q, got %q (%t)", ref, expected, name, ok)
+		}
+	}
+}
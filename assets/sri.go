@@ -0,0 +1,107 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"hash"
+	"html"
+	"html/template"
+	"io/fs"
+	"net/url"
+)
+
+// Integrity returns the Subresource Integrity value of the decompressed
+// content of the named file, i.e. its base64 encoded digest prefixed with the
+// algorithm, e.g. "sha384-", for use in integrity attributes. The hash function
+// set with WithHasher is used if it is one of those allowed for SRI, i.e.
+// "sha256", "sha384" or "sha512", otherwise SHA-384. It is memoized, as the
+// content never changes.
+func (compressed FileSystem) Integrity(path string) (string, error) {
+	name, err := compressed.resolvePath("open", path)
+	if err != nil {
+		return "", err
+	}
+	key := name + "\x00sri"
+	if integrity, ok := compressed.knownETag(key); ok {
+		return integrity, nil
+	}
+	content, err := fs.ReadFile(compressed, name)
+	if err != nil {
+		return "", err
+	}
+	algorithm, h := compressed.sriHash()
+	h.Write(content)
+	integrity := algorithm + "-" + base64.StdEncoding.EncodeToString(h.Sum(nil))
+	if compressed.etags != nil {
+		compressed.etags.Store(key, integrity)
+	}
+	return integrity, nil
+}
+
+// sriHash returns the name and a new instance of the hash function used for
+// Integrity.
+func (compressed FileSystem) sriHash() (string, hash.Hash) {
+	if compressed.opts != nil {
+		switch name := compressed.opts.hasherName; name {
+		case "sha256", "sha384", "sha512":
+			return name, compressed.opts.hasher()
+		}
+	}
+	return "sha384", sha512.New384()
+}
+
+// ScriptTag returns a script element loading the named file, with its
+// integrity attribute set, e.g. for use in templates as
+// {{ $.Assets.ScriptTag "app.js" }}. The file is referenced by its absolute
+// URL path, which is fingerprinted if the FileSystem resolves fingerprinted
+// names, see WithManifestPath.
+func (compressed FileSystem) ScriptTag(path string) (template.HTML, error) {
+	src, integrity, err := compressed.tagAttributes(path)
+	if err != nil {
+		return "", err
+	}
+	return template.HTML(`<script src="` + src + `" integrity="` + integrity + `" crossorigin="anonymous"></script>`), nil
+}
+
+// LinkTag returns a link element loading the named file as a stylesheet, with
+// its integrity attribute set, like ScriptTag.
+func (compressed FileSystem) LinkTag(path string) (template.HTML, error) {
+	href, integrity, err := compressed.tagAttributes(path)
+	if err != nil {
+		return "", err
+	}
+	return template.HTML(`<link rel="stylesheet" href="` + href + `" integrity="` + integrity + `" crossorigin="anonymous">`), nil
+}
+
+// tagAttributes returns the escaped URL and integrity attribute values for
+// referencing the named file.
+func (compressed FileSystem) tagAttributes(path string) (string, string, error) {
+	integrity, err := compressed.Integrity(path)
+	if err != nil {
+		return "", "", err
+	}
+	name, err := compressed.resolvePath("open", path)
+	if err != nil {
+		return "", "", err
+	}
+	if compressed.opts != nil && compressed.opts.manifest != nil {
+		if name, err = compressed.FingerprintedName(name); err != nil {
+			return "", "", err
+		}
+	}
+	u := &url.URL{Path: "/" + name}
+	return html.EscapeString(u.EscapedPath()), html.EscapeString(integrity), nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+)
+
+func TestIntegrity(t *testing.T) {
+	for path, expected := range map[string]string{
+		// openssl dgst -sha384 -binary | base64
+		"testdata/site/app.js":    "sha384-T2hO7zKkY8LxiAArzsPXNjINCGbwMTu1In0Aopy4beZlBxSrGoWPBhGCG2rh7418",
+		"testdata/site/style.css": "sha384-OOAocoe9URdSEbKSuC4UfBMdbWnyUwZrR5hgjomiMM3B/YKmdhn8l9D4QtETSrtx",
+	} {
+		for i := 0; i < 2; i++ {
+			integrity, err := testFS.Integrity(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if integrity != expected {
+				t.Fatalf("integrity of %s is wrong, expected %q, got %q", path, expected, integrity)
+			}
+		}
+	}
+	if _, err := testFS.Integrity("testdata/site/missing.js"); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
+
+func TestScriptAndLinkTag(t *testing.T) {
+	tmpl := template.Must(template.New("page").Parse(
+		`{{ .Assets.ScriptTag "testdata/site/app.js" }}{{ .Assets.LinkTag "testdata/site/style.css" }}`,
+	))
+	var out strings.Builder
+	if err := tmpl.Execute(&out, struct{ Assets FileSystem }{testFS}); err != nil {
+		t.Fatal(err)
+	}
+	expected := `<script src="/testdata/site/app.js" integrity="sha384-T2hO7zKkY8LxiAArzsPXNjINCGbwMTu1In0Aopy4beZlBxSrGoWPBhGCG2rh7418" crossorigin="anonymous"></script>` +
+		`<link rel="stylesheet" href="/testdata/site/style.css" integrity="sha384-OOAocoe9URdSEbKSuC4UfBMdbWnyUwZrR5hgjomiMM3B/YKmdhn8l9D4QtETSrtx" crossorigin="anonymous">`
+	if out.String() != expected {
+		t.Fatalf("output is wrong, expected\n%s\ngot\n%s", expected, out.String())
+	}
+
+	if _, err := testFS.ScriptTag("testdata/site/missing.js"); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
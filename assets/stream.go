@@ -0,0 +1,76 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"context"
+	"io"
+)
+
+// StreamTo decompresses the named file to w in chunks, without buffering its
+// entire content. After every chunk written, onProgress, if not nil, is called
+// with the number of bytes written so far and the total size, which is taken
+// from the function set with WithSizeFunc or the gzip trailer, and is -1 if
+// unknown. Streaming stops with the context's error once ctx is done.
+func (compressed FileSystem) StreamTo(ctx context.Context, w io.Writer, path string, onProgress func(sent, total int64)) error {
+	opts := &options{}
+	if compressed.opts != nil {
+		*opts = *compressed.opts
+	}
+	opts.streaming = true
+	compressed.opts = opts
+
+	f, err := compressed.OpenContext(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	total := stat.Size()
+
+	bufSize := defaultReadBufferSize
+	if opts.readBufferSize > 0 {
+		bufSize = opts.readBufferSize
+	}
+	buf := make([]byte, bufSize)
+	var sent int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := f.Read(buf)
+		if n > 0 {
+			m, werr := w.Write(buf[:n])
+			sent += int64(m)
+			if werr != nil {
+				return werr
+			}
+			if m != n {
+				return io.ErrShortWrite
+			}
+			if onProgress != nil {
+				onProgress(sent, total)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStreamTo(t *testing.T) {
+	fsys, err := NewWithOptions(siteFS, WithReadBufferSize(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, expected := range map[string]string{
+		"testdata/site/style.css": "body { margin: 0; }\n",
+		"testdata/site/app.js":    "console.log(\"app\");\n",
+	} {
+		var (
+			out      strings.Builder
+			progress [][2]int64
+		)
+		err := fsys.StreamTo(context.Background(), &out, path, func(sent, total int64) {
+			progress = append(progress, [2]int64{sent, total})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != expected {
+			t.Fatalf("content of %s is wrong, expected %q, got %q", path, expected, out.String())
+		}
+		expectedProgress := [][2]int64{{8, 20}, {16, 20}, {20, 20}}
+		if !reflect.DeepEqual(progress, expectedProgress) {
+			t.Fatalf("progress of %s is wrong, expected %v, got %v", path, expectedProgress, progress)
+		}
+	}
+
+	// Streaming mode isn't set for the FileSystem itself.
+	if fsys.opts.streaming {
+		t.Fatal("expected the options of the FileSystem to be unchanged")
+	}
+
+	if err := fsys.StreamTo(context.Background(), &strings.Builder{}, "testdata/site/missing.js", nil); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected a not exist error, got %v", err)
+	}
+}
+
+func TestStreamToCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	fsys, err := NewWithOptions(siteFS, WithReadBufferSize(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	err = fsys.StreamTo(ctx, &out, "testdata/site/style.css", func(sent, total int64) {
+		cancel()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the context error, got %v", err)
+	}
+	if out.String() != "body { m" {
+		t.Fatalf("expected streaming to stop after the first chunk, got %q", out.String())
+	}
+}
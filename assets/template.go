@@ -0,0 +1,131 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"sort"
+	texttemplate "text/template"
+)
+
+// ParseTemplates parses the html/template definitions from all files
+// matching the patterns, which are matched against the decompressed names of
+// the files, i.e. without any ".gz" suffix. Each template is named after the
+// base name of its file, as done by template.ParseFS.
+func (compressed FileSystem) ParseTemplates(patterns ...string) (*htmltemplate.Template, error) {
+	var t *htmltemplate.Template
+	err := compressed.readTemplates(patterns, func(name, content string) error {
+		if t == nil {
+			t = htmltemplate.New(name)
+		}
+		tmpl := t
+		if name != t.Name() {
+			tmpl = t.New(name)
+		}
+		_, err := tmpl.Parse(content)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ParseTextTemplates is like ParseTemplates, but for text/template.
+func (compressed FileSystem) ParseTextTemplates(patterns ...string) (*texttemplate.Template, error) {
+	var t *texttemplate.Template
+	err := compressed.readTemplates(patterns, func(name, content string) error {
+		if t == nil {
+			t = texttemplate.New(name)
+		}
+		tmpl := t
+		if name != t.Name() {
+			tmpl = t.New(name)
+		}
+		_, err := tmpl.Parse(content)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// readTemplates calls parse with the base name and content of every file
+// matching the patterns.
+func (compressed FileSystem) readTemplates(patterns []string, parse func(name, content string) error) error {
+	var names []string
+	for _, pattern := range patterns {
+		matches, err := compressed.globLogical(pattern)
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("assets: pattern matches no files: %#q", pattern)
+		}
+		names = append(names, matches...)
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("assets: no files named in call to ParseTemplates")
+	}
+
+	for _, name := range names {
+		content, err := compressed.ReadString(name)
+		if err != nil {
+			return err
+		}
+		if err := parse(path.Base(name), content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// globLogical returns the decompressed names of all files matching pattern.
+func (compressed FileSystem) globLogical(pattern string) ([]string, error) {
+	plain, err := fs.Glob(compressed.embed, pattern)
+	if err != nil {
+		return nil, err
+	}
+	var compressedNames []string
+	for _, se := range compressed.codings() {
+		matches, err := fs.Glob(compressed.embed, pattern+se.suffix)
+		if err != nil {
+			return nil, err
+		}
+		compressedNames = append(compressedNames, matches...)
+	}
+
+	seen := map[string]bool{}
+	var names []string
+	for _, name := range plain {
+		// Compressed files are covered by their decompressed name below.
+		if _, ok := compressed.trimDecodable(name); !ok && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, name := range compressedNames {
+		name, ok := compressed.trimDecodable(name)
+		if ok && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTemplates(t *testing.T) {
+	tmpl, err := testFS.ParseTemplates("testdata/templates/*.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	if err := tmpl.ExecuteTemplate(&b, "page.tmpl", "<world>"); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "Hello, &lt;world&gt;! <b>Bye</b>\n"; b.String() != expected {
+		t.Fatalf("output is wrong, expected %q, got %q", expected, b.String())
+	}
+	if tmpl.Lookup("greeting.tmpl") == nil {
+		t.Fatal("expected the gzipped template to be named after its decompressed name")
+	}
+}
+
+func TestParseTextTemplates(t *testing.T) {
+	tmpl, err := testFS.ParseTextTemplates("testdata/templates/greeting.tmpl", "testdata/templates/page.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	if err := tmpl.ExecuteTemplate(&b, "page.tmpl", "<world>"); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "Hello, <world>! <b>Bye</b>\n"; b.String() != expected {
+		t.Fatalf("output is wrong, expected %q, got %q", expected, b.String())
+	}
+}
+
+func TestParseTemplatesNoMatch(t *testing.T) {
+	if _, err := testFS.ParseTemplates("testdata/templates/*.missing"); err == nil {
+		t.Fatal("expected an error for a pattern without matches")
+	}
+	if _, err := testFS.ParseTemplates(); err == nil {
+		t.Fatal("expected an error without patterns")
+	}
+}
+
+func TestGlobLogical(t *testing.T) {
+	matches, err := testFS.globLogical("testdata/[bc]o*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"testdata/both", "testdata/compressed"}
+	if !reflect.DeepEqual(matches, expected) {
+		t.Fatalf("matches are wrong, expected %v, got %v", expected, matches)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"sync"
+	"time"
+)
+
+// timingWindowSize is the number of most recent decode durations averaged by
+// DecodeTimings.
+const timingWindowSize = 8
+
+// decodeTimings records decode durations per file, as enabled with
+// WithTimingCollection.
+type decodeTimings struct {
+	mtx       sync.Mutex
+	durations map[string]*timingWindow
+}
+
+// timingWindow is a ring buffer of the most recent decode durations of a file.
+type timingWindow struct {
+	durations [timingWindowSize]time.Duration
+	next      int
+	full      bool
+}
+
+func (t *decodeTimings) record(name string, d time.Duration) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	w, ok := t.durations[name]
+	if !ok {
+		w = &timingWindow{}
+		t.durations[name] = w
+	}
+	w.durations[w.next] = d
+	w.next = (w.next + 1) % timingWindowSize
+	if w.next == 0 {
+		w.full = true
+	}
+}
+
+// mean returns the mean of the durations in the window.
+func (w *timingWindow) mean() time.Duration {
+	n := w.next
+	if w.full {
+		n = timingWindowSize
+	}
+	var sum time.Duration
+	for _, d := range w.durations[:n] {
+		sum += d
+	}
+	return sum / time.Duration(n)
+}
+
+// DecodeTimings returns the mean time decompressing each file took over the
+// last 8 times it was opened, keyed by the name it was opened with. The result
+// is empty unless enabled with WithTimingCollection.
+func (compressed FileSystem) DecodeTimings() map[string]time.Duration {
+	timings := map[string]time.Duration{}
+	if compressed.opts == nil || compressed.opts.timings == nil {
+		return timings
+	}
+	t := compressed.opts.timings
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	for name, w := range t.durations {
+		timings[name] = w.mean()
+	}
+	return timings
+}
+
+// startTiming returns a function recording the time elapsed since calling
+// startTiming as a decode duration of name, if timings are collected.
+func (compressed FileSystem) startTiming(name string) func() {
+	if compressed.opts == nil || compressed.opts.timings == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() { compressed.opts.timings.record(name, time.Since(start)) }
+}
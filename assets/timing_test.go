@@ -0,0 +1,64 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"io/fs"
+	"testing"
+	"time"
+)
+
+func TestDecodeTimings(t *testing.T) {
+	fsys, err := NewWithOptions(EmbedFS, WithTimingCollection())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"testdata/compressed", "testdata/compressed", "testdata/large.bin", "testdata/uncompressed"} {
+		if _, err := fs.ReadFile(fsys, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	timings := fsys.DecodeTimings()
+	if len(timings) != 2 {
+		t.Fatalf("expected timings for 2 files, got %v", timings)
+	}
+	if timings["testdata/large.bin"] <= 0 {
+		t.Fatalf("expected a positive duration for the large file, got %v", timings)
+	}
+	if _, ok := timings["testdata/compressed"]; !ok {
+		t.Fatalf("expected a duration for the compressed file, got %v", timings)
+	}
+
+	if timings := testFS.DecodeTimings(); len(timings) != 0 {
+		t.Fatalf("expected no timings without collection, got %v", timings)
+	}
+}
+
+func TestTimingWindow(t *testing.T) {
+	timings := &decodeTimings{durations: map[string]*timingWindow{}}
+	timings.record("a", 2*time.Millisecond)
+	timings.record("a", 4*time.Millisecond)
+	if mean := timings.durations["a"].mean(); mean != 3*time.Millisecond {
+		t.Fatalf("mean is wrong, expected %v, got %v", 3*time.Millisecond, mean)
+	}
+
+	// Only the most recent durations are considered.
+	for i := 0; i < timingWindowSize; i++ {
+		timings.record("a", time.Millisecond)
+	}
+	if mean := timings.durations["a"].mean(); mean != time.Millisecond {
+		t.Fatalf("mean is wrong, expected %v, got %v", time.Millisecond, mean)
+	}
+}
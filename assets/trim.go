@@ -0,0 +1,124 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// TrimPrefix returns an fs.FS rooted at prefix within the FileSystem. Every
+// name passed to Open, ReadDir and Glob has prefix prepended, and names
+// returned by Glob have it removed again. Unlike fs.Sub, this is a pure path
+// rewrite, which makes it usable with helpers like template.ParseFS that only
+// know about the trimmed names.
+func (compressed FileSystem) TrimPrefix(prefix string) fs.FS {
+	return trimmedFS{fsys: compressed, prefix: path.Clean(prefix)}
+}
+
+type trimmedFS struct {
+	fsys   fs.FS
+	prefix string
+}
+
+// fullName returns the name in the underlying FileSystem.
+func (t trimmedFS) fullName(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return path.Join(t.prefix, name), nil
+}
+
+// shortName returns the name as seen by users of the trimmed view.
+func (t trimmedFS) shortName(name string) (string, bool) {
+	if t.prefix == "." {
+		return name, true
+	}
+	if name == t.prefix {
+		return ".", true
+	}
+	if !strings.HasPrefix(name, t.prefix+"/") {
+		return "", false
+	}
+	return name[len(t.prefix)+1:], true
+}
+
+// fixErr reports errors in terms of the trimmed names.
+func (t trimmedFS) fixErr(err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		if short, ok := t.shortName(pathErr.Path); ok {
+			pathErr.Path = short
+		}
+	}
+	return err
+}
+
+// Open implements the fs.FS interface.
+func (t trimmedFS) Open(name string) (fs.File, error) {
+	full, err := t.fullName("open", name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := t.fsys.Open(full)
+	return f, t.fixErr(err)
+}
+
+// ReadDir implements the fs.ReadDirFS interface.
+func (t trimmedFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	full, err := t.fullName("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := fs.ReadDir(t.fsys, full)
+	return entries, t.fixErr(err)
+}
+
+// Glob implements the fs.GlobFS interface.
+func (t trimmedFS) Glob(pattern string) ([]string, error) {
+	// Check the pattern itself, as errors for the prefixed one would be
+	// confusing.
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	if t.prefix != "." {
+		pattern = path.Join(escapeGlob(t.prefix), pattern)
+	}
+	matches, err := fs.Glob(t.fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	names := matches[:0]
+	for _, m := range matches {
+		if short, ok := t.shortName(m); ok {
+			names = append(names, short)
+		}
+	}
+	return names, nil
+}
+
+// escapeGlob escapes all characters of name that have a meaning in patterns.
+func escapeGlob(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assets
+
+import (
+	"errors"
+	"html/template"
+	"io"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTrimPrefix(t *testing.T) {
+	trimmed := testFS.TrimPrefix("testdata/ui/dist")
+
+	f, err := trimmed.Open("index.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "Hello, {{.}}!\n" {
+		t.Fatalf("content is wrong, got %q", string(content))
+	}
+
+	entries, err := fs.ReadDir(trimmed, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if expected := []string{"index.tmpl", "page.tmpl"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("entries are wrong, expected %v, got %v", expected, names)
+	}
+
+	matches, err := fs.Glob(trimmed, "*.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"index.tmpl", "page.tmpl"}; !reflect.DeepEqual(matches, expected) {
+		t.Fatalf("matches are wrong, expected %v, got %v", expected, matches)
+	}
+}
+
+func TestTrimPrefixErrors(t *testing.T) {
+	trimmed := testFS.TrimPrefix("testdata")
+
+	_, err := trimmed.Open("missing")
+	var pathErr *fs.PathError
+	if !errors.As(err, &pathErr) || !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected a not exist *fs.PathError, got %v", err)
+	}
+	if pathErr.Path != "missing" {
+		t.Fatalf("expected the error to refer to the trimmed name, got %q", pathErr.Path)
+	}
+
+	if _, err := trimmed.Open("../testdata/both"); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("expected fs.ErrInvalid, got %v", err)
+	}
+	if _, err := fs.Glob(trimmed, "["); err == nil {
+		t.Fatal("expected an error for a malformed pattern")
+	}
+}
+
+func TestTrimPrefixParseFS(t *testing.T) {
+	tmpl, err := template.ParseFS(testFS.TrimPrefix("testdata/ui/dist"), "*.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	if err := tmpl.ExecuteTemplate(&b, "index.tmpl", "world"); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "Hello, world!\n" {
+		t.Fatalf("output is wrong, got %q", b.String())
+	}
+}
@@ -17,22 +17,20 @@
 package config
 
 import (
-	"encoding/json"
 	"net/http"
 	"path/filepath"
-)
 
-const secretToken = "<secret>"
+	"github.com/prometheus/common/secrets"
+)
 
-// Secret special type for storing secrets.
+// Secret special type for storing secrets. It marshals like secrets.Secret,
+// which it can be converted to and from, but unlike that it isn't redacted when
+// formatted with the fmt package, as existing users rely on that.
 type Secret string
 
 // MarshalYAML implements the yaml.Marshaler interface for Secrets.
 func (s Secret) MarshalYAML() (interface{}, error) {
-	if s != "" {
-		return secretToken, nil
-	}
-	return nil, nil
+	return secrets.Secret(s).MarshalYAML()
 }
 
 // UnmarshalYAML implements the yaml.Unmarshaler interface for Secrets.
@@ -43,10 +41,7 @@ func (s *Secret) UnmarshalYAML(unmarshal func(interface{}) error) error {
 
 // MarshalJSON implements the json.Marshaler interface for Secret.
 func (s Secret) MarshalJSON() ([]byte, error) {
-	if len(s) == 0 {
-		return json.Marshal("")
-	}
-	return json.Marshal(secretToken)
+	return secrets.Secret(s).MarshalJSON()
 }
 
 type Header map[string][]Secret
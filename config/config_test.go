@@ -16,11 +16,14 @@ package config
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"reflect"
 	"testing"
 
 	"gopkg.in/yaml.v2"
+
+	"github.com/prometheus/common/secrets"
 )
 
 func TestJSONMarshalSecret(t *testing.T) {
@@ -57,6 +60,23 @@ func TestJSONMarshalSecret(t *testing.T) {
 	}
 }
 
+// Secrets are only redacted when marshalled, formatting them yields the value,
+// unlike secrets.Secret.
+func TestFormatSecret(t *testing.T) {
+	s := Secret("test")
+	for _, actual := range []string{fmt.Sprint(s), fmt.Sprintf("%s", s), fmt.Sprintf("%v", s)} {
+		if actual != "test" {
+			t.Fatalf("expected the secret value, got %q", actual)
+		}
+	}
+	if actual := fmt.Sprint(secrets.Secret(s)); actual != "<secret>" {
+		t.Fatalf("expected secrets.Secret to be redacted, got %q", actual)
+	}
+	if back := Secret(secrets.Secret(s)); back != s {
+		t.Fatalf("expected the value to survive the conversion, got %q", string(back))
+	}
+}
+
 func TestHeaderHTTPHeader(t *testing.T) {
 	testcases := map[string]struct {
 		header   Header
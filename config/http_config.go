@@ -194,7 +194,7 @@ func (u URL) Redacted() string {
 
 	ru := *u.URL
 	if _, ok := ru.User.Password(); ok {
-		// We can not use secretToken because it would be escaped.
+		// We can not use "<secret>" because it would be escaped.
 		ru.User = url.UserPassword(ru.User.Username(), "xxxxx")
 	}
 	return ru.String()
@@ -0,0 +1,288 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package httputil provides HTTP middleware.
+package httputil
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const defaultMinSize = 1024
+
+// DefaultContentTypes are the media types compressed by CompressionHandler
+// unless configured otherwise. A trailing "/*" matches all subtypes.
+var DefaultContentTypes = []string{
+	"text/*",
+	"application/javascript",
+	"application/json",
+	"application/openmetrics-text",
+	"application/xml",
+	"image/svg+xml",
+}
+
+// CompressionOptions configures CompressionHandler.
+type CompressionOptions struct {
+	// MinSize is the size in bytes below which response bodies aren't
+	// compressed. It defaults to 1KiB.
+	MinSize int
+	// ContentTypes are the media types of the responses to compress. It
+	// defaults to DefaultContentTypes.
+	ContentTypes []string
+	// Zstd, if not nil, returns a zstd encoder writing to w, making zstd
+	// preferred over gzip for clients accepting both.
+	Zstd func(w io.Writer) io.WriteCloser
+}
+
+type encoder struct {
+	encoding string
+	new      func(w io.Writer) io.WriteCloser
+}
+
+// CompressionHandler returns a handler compressing the responses of next with
+// the content coding negotiated from the Accept-Encoding request header.
+// Responses that already carry a Content-Encoding, responses to range
+// requests and partial responses, those smaller than opts.MinSize and those of
+// other media types than opts.ContentTypes are passed on unmodified. Flushing the response commits to compressing it, and
+// hijacking the connection is passed through.
+func CompressionHandler(next http.Handler, opts CompressionOptions) http.Handler {
+	if opts.MinSize <= 0 {
+		opts.MinSize = defaultMinSize
+	}
+	if opts.ContentTypes == nil {
+		opts.ContentTypes = DefaultContentTypes
+	}
+	var encoders []encoder
+	if opts.Zstd != nil {
+		encoders = append(encoders, encoder{"zstd", opts.Zstd})
+	}
+	encoders = append(encoders, encoder{"gzip", func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		enc, ok := negotiate(r.Header.Get("Accept-Encoding"), encoders)
+		if !ok || r.Header.Get("Range") != "" {
+			// Ranges refer to the identity encoded body.
+			next.ServeHTTP(w, r)
+			return
+		}
+		cw := &compressWriter{ResponseWriter: w, opts: &opts, enc: enc}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiate returns the encoder out of encoders that is preferred by a client
+// sending the given Accept-Encoding header value.
+func negotiate(acceptEncoding string, encoders []encoder) (encoder, bool) {
+	accepted := map[string]float64{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		params := strings.Split(part, ";")
+		enc := strings.ToLower(strings.TrimSpace(params[0]))
+		if enc == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.TrimSpace(k) != "q" {
+				continue
+			}
+			var err error
+			if q, err = strconv.ParseFloat(strings.TrimSpace(v), 64); err != nil {
+				q = 0
+			}
+		}
+		accepted[enc] = q
+	}
+
+	var (
+		best  encoder
+		bestQ float64
+	)
+	for _, e := range encoders {
+		q, ok := accepted[e.encoding]
+		if !ok {
+			q = accepted["*"]
+		}
+		if q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// compressWriter holds back the response body until it is known whether it
+// is to be compressed.
+type compressWriter struct {
+	http.ResponseWriter
+	opts *CompressionOptions
+	enc  encoder
+
+	status      int
+	buf         []byte
+	encoder     io.WriteCloser
+	passthrough bool
+	wroteHeader bool
+	hijacked    bool
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (cw *compressWriter) WriteHeader(status int) {
+	if status >= 100 && status < 200 {
+		// Informational responses are sent right away.
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if cw.status == 0 {
+		cw.status = status
+	}
+}
+
+// Write implements http.ResponseWriter.
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	switch {
+	case cw.encoder != nil:
+		return cw.encoder.Write(p)
+	case cw.passthrough:
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= cw.opts.MinSize {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush implements http.Flusher.
+func (cw *compressWriter) Flush() {
+	if cw.encoder == nil && !cw.passthrough {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		if err := cw.decide(true); err != nil {
+			return
+		}
+	}
+	if f, ok := cw.encoder.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("httputil: underlying ResponseWriter doesn't support hijacking")
+	}
+	conn, rw, err := hj.Hijack()
+	if err == nil {
+		cw.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying ResponseWriter, for use by
+// http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// decide starts compressing the body if it qualifies, or passes it through,
+// writing out the buffered part either way. Compression is only considered if
+// compress is set, i.e. the body is large enough or being streamed.
+func (cw *compressWriter) decide(compress bool) error {
+	h := cw.Header()
+	if h.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+	if !compress || h.Get("Content-Encoding") != "" || !bodyAllowed(cw.status) || isPartial(cw.status, h) || !cw.allowedType(h.Get("Content-Type")) {
+		cw.passthrough = true
+		cw.writeHeader()
+		buf := cw.buf
+		cw.buf = nil
+		_, err := cw.ResponseWriter.Write(buf)
+		return err
+	}
+
+	h.Set("Content-Encoding", cw.enc.encoding)
+	h.Del("Content-Length")
+	cw.writeHeader()
+	cw.encoder = cw.enc.new(cw.ResponseWriter)
+	buf := cw.buf
+	cw.buf = nil
+	_, err := cw.encoder.Write(buf)
+	return err
+}
+
+// allowedType reports whether responses of the content type are compressed.
+func (cw *compressWriter) allowedType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range cw.opts.ContentTypes {
+		if t == mediaType || (strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPartial reports whether the response holds a range of the identity
+// encoded body, which compressing would corrupt.
+func isPartial(status int, h http.Header) bool {
+	return status == http.StatusPartialContent || h.Get("Content-Range") != ""
+}
+
+// bodyAllowed reports whether a response with the status may have a body.
+func bodyAllowed(status int) bool {
+	return status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// close writes out any buffered body and finishes the compressed stream.
+func (cw *compressWriter) close() error {
+	switch {
+	case cw.hijacked:
+		return nil
+	case cw.encoder != nil:
+		return cw.encoder.Close()
+	case cw.passthrough || cw.status == 0:
+		return nil
+	}
+	return cw.decide(false)
+}
+
+func (cw *compressWriter) writeHeader() {
+	if !cw.wroteHeader {
+		cw.wroteHeader = true
+		cw.ResponseWriter.WriteHeader(cw.status)
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httputil
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func gunzip(t *testing.T, b []byte) string {
+	t.Helper()
+	gr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestCompressionHandler(t *testing.T) {
+	large := strings.Repeat("compress me ", 200)
+	cases := []struct {
+		name             string
+		accept           string
+		contentType      string
+		contentEncoding  string
+		body             string
+		expectedEncoding string
+	}{
+		{name: "gzip", accept: "gzip, deflate", contentType: "text/plain", body: large, expectedEncoding: "gzip"},
+		{name: "sniffed type", accept: "gzip", body: large, expectedEncoding: "gzip"},
+		{name: "not accepted", accept: "br", contentType: "text/plain", body: large},
+		{name: "rejected with q=0", accept: "gzip;q=0", contentType: "text/plain", body: large},
+		{name: "small", accept: "gzip", contentType: "text/plain", body: "small"},
+		{name: "type not allowed", accept: "gzip", contentType: "image/png", body: large},
+		{name: "already encoded", accept: "gzip", contentType: "text/plain", contentEncoding: "br", body: large, expectedEncoding: "br"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			handler := CompressionHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if c.contentType != "" {
+					w.Header().Set("Content-Type", c.contentType)
+				}
+				if c.contentEncoding != "" {
+					w.Header().Set("Content-Encoding", c.contentEncoding)
+				}
+				// Write in chunks, crossing the size threshold.
+				for i := 0; i < len(c.body); i += 100 {
+					end := i + 100
+					if end > len(c.body) {
+						end = len(c.body)
+					}
+					io.WriteString(w, c.body[i:end])
+				}
+			}), CompressionOptions{})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", c.accept)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if enc := rec.Header().Get("Content-Encoding"); enc != c.expectedEncoding {
+				t.Fatalf("encoding is wrong, expected %q, got %q", c.expectedEncoding, enc)
+			}
+			if vary := rec.Header().Get("Vary"); vary != "Accept-Encoding" {
+				t.Fatalf("expected Vary: Accept-Encoding, got %q", vary)
+			}
+			body := rec.Body.String()
+			if c.expectedEncoding == "gzip" {
+				body = gunzip(t, rec.Body.Bytes())
+			}
+			if body != c.body {
+				t.Fatalf("body is wrong, got %q", body)
+			}
+		})
+	}
+}
+
+func TestCompressionHandlerZstd(t *testing.T) {
+	// A stand-in encoder, as there is no zstd encoder in the standard library.
+	zstd := func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }
+	handler := CompressionHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, strings.Repeat("a", 2048))
+	}), CompressionOptions{Zstd: zstd, ContentTypes: []string{"text/plain"}})
+
+	for accept, expected := range map[string]string{
+		"gzip, zstd":       "zstd",
+		"gzip, zstd;q=0.5": "gzip",
+		"*":                "zstd",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", accept)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if enc := rec.Header().Get("Content-Encoding"); enc != expected {
+			t.Fatalf("%s: encoding is wrong, expected %q, got %q", accept, expected, enc)
+		}
+	}
+}
+
+func TestCompressionHandlerFlush(t *testing.T) {
+	handler := CompressionHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: 1\n\n")
+		w.(http.Flusher).Flush()
+		io.WriteString(w, "data: 2\n\n")
+	}), CompressionOptions{})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if !rec.Flushed {
+		t.Fatal("expected the flush to be passed through")
+	}
+	if enc := rec.Header().Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("expected streamed responses to be compressed, got %q", enc)
+	}
+	if body := gunzip(t, rec.Body.Bytes()); body != "data: 1\n\ndata: 2\n\n" {
+		t.Fatalf("body is wrong, got %q", body)
+	}
+}
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	return nil, nil, nil
+}
+
+func TestCompressionHandlerHijack(t *testing.T) {
+	handler := CompressionHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := w.(http.Hijacker).Hijack(); err != nil {
+			t.Fatal(err)
+		}
+	}), CompressionOptions{})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+	handler.ServeHTTP(rec, req)
+	if !rec.hijacked {
+		t.Fatal("expected the hijack to be passed through")
+	}
+	if rec.Body.Len() != 0 {
+		t.Fatalf("expected nothing to be written after hijacking, got %q", rec.Body.String())
+	}
+}
+
+func TestCompressionHandlerNoBody(t *testing.T) {
+	handler := CompressionHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}), CompressionOptions{})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("status is wrong, expected %d, got %d", http.StatusNotModified, rec.Code)
+	}
+	if enc := rec.Header().Get("Content-Encoding"); enc != "" {
+		t.Fatalf("expected no encoding, got %q", enc)
+	}
+}
+
+func TestCompressionHandlerRange(t *testing.T) {
+	content := strings.Repeat("0123456789", 1000)
+	handler := CompressionHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/partial" {
+			// A partial response to a request without Range, e.g. rewritten
+			// by a proxy.
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(content)-1, 2*len(content)))
+			w.WriteHeader(http.StatusPartialContent)
+			io.WriteString(w, content)
+			return
+		}
+		http.ServeContent(w, r, "content.txt", time.Time{}, strings.NewReader(content))
+	}), CompressionOptions{})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=10-5009")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusPartialContent {
+		t.Fatalf("status is wrong, expected %d, got %d", http.StatusPartialContent, rec.Code)
+	}
+	if enc := rec.Header().Get("Content-Encoding"); enc != "" {
+		t.Fatalf("expected no encoding, got %q", enc)
+	}
+	if body := rec.Body.String(); body != content[10:5010] {
+		t.Fatalf("body is wrong, got %d bytes", len(body))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/partial", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if enc := rec.Header().Get("Content-Encoding"); enc != "" {
+		t.Fatalf("expected no encoding for a partial response, got %q", enc)
+	}
+	if rec.Body.String() != content {
+		t.Fatal("body is wrong")
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricsfmt
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/prometheus/common/internal/bitbucket.org/ww/goautoneg"
+)
+
+// Content types of the supported formats.
+const (
+	OpenMetricsContentType = `application/openmetrics-text; version=1.0.0; charset=utf-8`
+	JSONContentType        = `application/json`
+)
+
+// Negotiate returns the content type of the format to use for a request with
+// the given Accept header. JSON is used if it is accepted with a higher
+// quality than OpenMetrics or plain text, otherwise OpenMetrics.
+func Negotiate(accept string) string {
+	var jsonQ, textQ float64
+	for _, ac := range goautoneg.ParseAccept(accept) {
+		switch ac.Type + "/" + ac.SubType {
+		case "application/json":
+			jsonQ = max64(jsonQ, ac.Q)
+		case "application/openmetrics-text", "text/plain", "*/*":
+			textQ = max64(textQ, ac.Q)
+		}
+	}
+	if jsonQ > textQ {
+		return JSONContentType
+	}
+	return OpenMetricsContentType
+}
+
+func max64(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Handler returns an http.Handler serving the families returned by gather,
+// e.g. Registry.Gather, in the format negotiated from the Accept header of
+// each request.
+func Handler(gather func() []Family) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType := Negotiate(r.Header.Get("Accept"))
+		write := WriteOpenMetrics
+		if contentType == JSONContentType {
+			write = WriteJSON
+		}
+		// Buffer the output, so that errors can still be reported.
+		var buf bytes.Buffer
+		if err := write(&buf, gather()); err != nil {
+			http.Error(w, "error encoding metrics: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Add("Vary", "Accept")
+		w.Write(buf.Bytes())
+	})
+}
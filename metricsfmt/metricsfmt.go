@@ -0,0 +1,211 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metricsfmt provides a small exposition path for application metrics
+// snapshots, encoding them as OpenMetrics text or JSON, without depending on a
+// full metrics stack.
+package metricsfmt
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+// MetricType is the type of a metric family.
+type MetricType string
+
+// The supported metric types.
+const (
+	CounterType MetricType = "counter"
+	GaugeType   MetricType = "gauge"
+	UnknownType MetricType = "unknown"
+)
+
+// Sample is a single value of a metric family, identified by its labels.
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// Family is a set of samples sharing a name, help text and type. Counter
+// names must not include the "_total" suffix, it is added when encoding.
+type Family struct {
+	Name    string
+	Help    string
+	Type    MetricType
+	Samples []Sample
+}
+
+// Validate returns an error if the family has an invalid name, type or
+// labels, or if two samples have the same labels.
+func (f Family) Validate() error {
+	if !model.IsValidMetricName(model.LabelValue(f.Name)) {
+		return fmt.Errorf("invalid metric name %q", f.Name)
+	}
+	if f.Type == CounterType && strings.HasSuffix(f.Name, "_total") {
+		return fmt.Errorf("counter name %q must not end with _total", f.Name)
+	}
+	switch f.Type {
+	case CounterType, GaugeType, UnknownType:
+	default:
+		return fmt.Errorf("invalid type %q of metric %q", f.Type, f.Name)
+	}
+	seen := map[string]bool{}
+	for _, s := range f.Samples {
+		for name := range s.Labels {
+			if !model.LabelName(name).IsValid() {
+				return fmt.Errorf("invalid label name %q of metric %q", name, f.Name)
+			}
+		}
+		key := labelsString(s.Labels)
+		if seen[key] {
+			return fmt.Errorf("duplicate sample %s%s", f.Name, key)
+		}
+		seen[key] = true
+		if f.Type == CounterType && (s.Value < 0 || math.IsNaN(s.Value)) {
+			return fmt.Errorf("invalid value %v of counter %s%s", s.Value, f.Name, key)
+		}
+	}
+	return nil
+}
+
+// FromMap returns an untyped family for each entry of samples, keyed by
+// metric name, sorted by name.
+func FromMap(samples map[string]float64) []Family {
+	families := make([]Family, 0, len(samples))
+	for name, v := range samples {
+		families = append(families, Family{
+			Name:    name,
+			Type:    UnknownType,
+			Samples: []Sample{{Value: v}},
+		})
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].Name < families[j].Name })
+	return families
+}
+
+// WriteOpenMetrics writes the families to w in the OpenMetrics text format,
+// terminated by "# EOF".
+func WriteOpenMetrics(w io.Writer, families []Family) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		if err := f.Validate(); err != nil {
+			return err
+		}
+		if f.Help != "" {
+			fmt.Fprintf(bw, "# HELP %s %s\n", f.Name, helpEscaper.Replace(f.Help))
+		}
+		fmt.Fprintf(bw, "# TYPE %s %s\n", f.Name, f.Type)
+		name := f.Name
+		if f.Type == CounterType {
+			name += "_total"
+		}
+		for _, s := range sortedSamples(f.Samples) {
+			fmt.Fprintf(bw, "%s%s %s\n", name, labelsString(s.Labels), formatValue(s.Value))
+		}
+	}
+	bw.WriteString("# EOF\n")
+	return bw.Flush()
+}
+
+type jsonSample struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	// Value is a string, as JSON can't represent NaN and infinities.
+	Value string `json:"value"`
+}
+
+type jsonFamily struct {
+	Name    string       `json:"name"`
+	Help    string       `json:"help,omitempty"`
+	Type    MetricType   `json:"type"`
+	Samples []jsonSample `json:"samples"`
+}
+
+// WriteJSON writes the families to w as a JSON array. Values are encoded as
+// strings in the format used by OpenMetrics, e.g. "1.5" or "+Inf".
+func WriteJSON(w io.Writer, families []Family) error {
+	out := make([]jsonFamily, 0, len(families))
+	for _, f := range families {
+		if err := f.Validate(); err != nil {
+			return err
+		}
+		jf := jsonFamily{Name: f.Name, Help: f.Help, Type: f.Type, Samples: []jsonSample{}}
+		for _, s := range sortedSamples(f.Samples) {
+			jf.Samples = append(jf.Samples, jsonSample{Labels: s.Labels, Value: formatValue(s.Value)})
+		}
+		out = append(out, jf)
+	}
+	return json.NewEncoder(w).Encode(out)
+}
+
+var (
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// labelsString returns the labels in the text format, e.g. `{a="1",b="2"}`,
+// sorted by name, or an empty string if there are none.
+func labelsString(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(labelValueEscaper.Replace(labels[name]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// sortedSamples returns a copy of samples sorted by their labels.
+func sortedSamples(samples []Sample) []Sample {
+	sorted := make([]Sample, len(samples))
+	copy(sorted, samples)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return labelsString(sorted[i].Labels) < labelsString(sorted[j].Labels)
+	})
+	return sorted
+}
+
+// formatValue formats v like OpenMetrics does.
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,132 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricsfmt
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func testRegistry(t *testing.T) *Registry {
+	r := NewRegistry()
+	c, err := r.NewCounter("requests", "Requests handled.\nBy code.", map[string]string{"code": "200"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Add(3)
+	c, err = r.NewCounter("requests", "Requests handled.\nBy code.", map[string]string{"code": "500"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Inc()
+	g, err := r.NewGauge("temperature", "", map[string]string{"room": `a "big" one`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Set(21.5)
+	g.Dec()
+	return r
+}
+
+func TestWriteOpenMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteOpenMetrics(&buf, testRegistry(t).Gather()); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# HELP requests Requests handled.\nBy code.
+# TYPE requests counter
+requests_total{code="200"} 3
+requests_total{code="500"} 1
+# TYPE temperature gauge
+temperature{room="a \"big\" one"} 20.5
+# EOF
+`
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	families := FromMap(map[string]float64{"up": 1, "ratio": math.Inf(1)})
+	if err := WriteJSON(&buf, families); err != nil {
+		t.Fatal(err)
+	}
+	expected := `[{"name":"ratio","type":"unknown","samples":[{"value":"+Inf"}]},{"name":"up","type":"unknown","samples":[{"value":"1"}]}]` + "\n"
+	if buf.String() != expected {
+		t.Errorf("expected %s, got %s", expected, buf.String())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, f := range []Family{
+		{Name: "0bad", Type: GaugeType},
+		{Name: "requests_total", Type: CounterType},
+		{Name: "requests", Type: "histogram"},
+		{Name: "requests", Type: CounterType, Samples: []Sample{{Value: -1}}},
+		{Name: "requests", Type: GaugeType, Samples: []Sample{{Labels: map[string]string{"bad-name": ""}}}},
+		{Name: "requests", Type: GaugeType, Samples: []Sample{{}, {}}},
+	} {
+		if err := f.Validate(); err == nil {
+			t.Errorf("expected an error for %+v", f)
+		}
+	}
+}
+
+func TestRegistryConflicts(t *testing.T) {
+	r := testRegistry(t)
+	if _, err := r.NewCounter("requests", "Requests handled.\nBy code.", map[string]string{"code": "200"}); err == nil {
+		t.Error("expected an error for duplicate labels")
+	}
+	if _, err := r.NewGauge("requests", "Requests handled.\nBy code.", nil); err == nil {
+		t.Error("expected an error for a different type")
+	}
+	if _, err := r.NewCounter("requests", "Other help.", nil); err == nil {
+		t.Error("expected an error for a different help text")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	h := Handler(testRegistry(t).Gather)
+	for accept, expected := range map[string]string{
+		"":                                   OpenMetricsContentType,
+		"application/openmetrics-text":       OpenMetricsContentType,
+		"application/json":                   JSONContentType,
+		"text/plain;q=0.5, application/json": JSONContentType,
+		"application/json;q=0.5, */*":        OpenMetricsContentType,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: unexpected status %d", accept, rec.Code)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != expected {
+			t.Errorf("%q: expected content type %q, got %q", accept, expected, ct)
+		}
+	}
+
+	bad := Handler(func() []Family { return []Family{{Name: "0bad", Type: GaugeType}} })
+	rec := httptest.NewRecorder()
+	bad.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500 for invalid metrics, got %d", rec.Code)
+	}
+}
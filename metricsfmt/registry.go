@@ -0,0 +1,156 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricsfmt
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Registry holds counters and gauges, and gathers snapshots of their values
+// as families. It is safe for concurrent use.
+type Registry struct {
+	mtx      sync.RWMutex
+	families map[string]*registeredFamily
+}
+
+type registeredFamily struct {
+	help    string
+	typ     MetricType
+	metrics map[string]*registeredMetric
+}
+
+type registeredMetric struct {
+	labels map[string]string
+	bits   *atomic.Uint64
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{families: map[string]*registeredFamily{}}
+}
+
+// Counter is a monotonically increasing value.
+type Counter struct {
+	bits *atomic.Uint64
+}
+
+// Inc increments the counter by 1.
+func (c Counter) Inc() { c.Add(1) }
+
+// Add adds v to the counter. It panics if v is negative.
+func (c Counter) Add(v float64) {
+	if v < 0 {
+		panic("metricsfmt: counter cannot decrease")
+	}
+	addFloat(c.bits, v)
+}
+
+// Value returns the current value of the counter.
+func (c Counter) Value() float64 { return math.Float64frombits(c.bits.Load()) }
+
+// Gauge is a value which can go up and down.
+type Gauge struct {
+	bits *atomic.Uint64
+}
+
+// Set sets the gauge to v.
+func (g Gauge) Set(v float64) { g.bits.Store(math.Float64bits(v)) }
+
+// Add adds v, which may be negative, to the gauge.
+func (g Gauge) Add(v float64) { addFloat(g.bits, v) }
+
+// Inc increments the gauge by 1.
+func (g Gauge) Inc() { g.Add(1) }
+
+// Dec decrements the gauge by 1.
+func (g Gauge) Dec() { g.Add(-1) }
+
+// Value returns the current value of the gauge.
+func (g Gauge) Value() float64 { return math.Float64frombits(g.bits.Load()) }
+
+func addFloat(bits *atomic.Uint64, v float64) {
+	for {
+		old := bits.Load()
+		if bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// NewCounter registers and returns a counter with the given name, help text
+// and labels. Counters of the same name must have the same help text and
+// distinct labels. The name must not include the "_total" suffix.
+func (r *Registry) NewCounter(name, help string, labels map[string]string) (Counter, error) {
+	bits, err := r.register(name, help, CounterType, labels)
+	return Counter{bits}, err
+}
+
+// NewGauge registers and returns a gauge with the given name, help text and
+// labels. Gauges of the same name must have the same help text and distinct
+// labels.
+func (r *Registry) NewGauge(name, help string, labels map[string]string) (Gauge, error) {
+	bits, err := r.register(name, help, GaugeType, labels)
+	return Gauge{bits}, err
+}
+
+func (r *Registry) register(name, help string, typ MetricType, labels map[string]string) (*atomic.Uint64, error) {
+	copied := make(map[string]string, len(labels))
+	for k, v := range labels {
+		copied[k] = v
+	}
+	if err := (Family{Name: name, Type: typ, Samples: []Sample{{Labels: copied}}}).Validate(); err != nil {
+		return nil, err
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	f, ok := r.families[name]
+	if !ok {
+		f = &registeredFamily{help: help, typ: typ, metrics: map[string]*registeredMetric{}}
+		r.families[name] = f
+	}
+	if f.typ != typ || f.help != help {
+		return nil, fmt.Errorf("metric %q already registered as %s with a different help text or type", name, f.typ)
+	}
+	key := labelsString(copied)
+	if _, ok := f.metrics[key]; ok {
+		return nil, fmt.Errorf("duplicate metric %s%s", name, key)
+	}
+	m := &registeredMetric{labels: copied, bits: &atomic.Uint64{}}
+	f.metrics[key] = m
+	return m.bits, nil
+}
+
+// Gather returns a snapshot of all registered metrics, sorted by name.
+func (r *Registry) Gather() []Family {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	families := make([]Family, 0, len(r.families))
+	for name, f := range r.families {
+		family := Family{Name: name, Help: f.help, Type: f.typ}
+		for _, m := range f.metrics {
+			family.Samples = append(family.Samples, Sample{
+				Labels: m.labels,
+				Value:  math.Float64frombits(m.bits.Load()),
+			})
+		}
+		families = append(families, family)
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].Name < families[j].Name })
+	return families
+}
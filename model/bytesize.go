@@ -0,0 +1,165 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is an amount of bytes. It is used to parse human readable sizes
+// such as "512MB" or "1.5GiB" from YAML, JSON and flags.
+type ByteSize int64
+
+// byteUnits lists the accepted units from the biggest to the smallest, so
+// that String picks the largest unit representing a size exactly.
+var byteUnits = []struct {
+	name string
+	mult int64
+}{
+	{"EiB", 1 << 60},
+	{"EB", 1e18},
+	{"PiB", 1 << 50},
+	{"PB", 1e15},
+	{"TiB", 1 << 40},
+	{"TB", 1e12},
+	{"GiB", 1 << 30},
+	{"GB", 1e9},
+	{"MiB", 1 << 20},
+	{"MB", 1e6},
+	{"KiB", 1 << 10},
+	{"KB", 1e3},
+	{"B", 1},
+}
+
+// ParseByteSize parses a string such as "512MB" or "1.5GiB" into a ByteSize.
+// Decimal units (KB, MB, ...) are powers of 1000 and binary units (KiB,
+// MiB, ...) powers of 1024. A number without a unit is an amount of bytes.
+func ParseByteSize(s string) (ByteSize, error) {
+	if s == "" {
+		return 0, errors.New("empty byte size string")
+	}
+
+	i := 0
+	for ; i < len(s) && (isdigit(s[i]) || s[i] == '.'); i++ {
+	}
+	num, unit := s[:i], strings.TrimSpace(s[i:])
+	if num == "" {
+		return 0, fmt.Errorf("not a valid byte size string: %q", s)
+	}
+
+	mult := int64(-1)
+	if unit == "" {
+		mult = 1
+	}
+	for _, u := range byteUnits {
+		if u.name == unit {
+			mult = u.mult
+			break
+		}
+	}
+	if mult < 0 {
+		return 0, fmt.Errorf("unknown unit %q in byte size %q", unit, s)
+	}
+
+	if !strings.Contains(num, ".") {
+		v, err := strconv.ParseInt(num, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("not a valid byte size string: %q", s)
+		}
+		if v > math.MaxInt64/mult {
+			return 0, fmt.Errorf("byte size out of range: %q", s)
+		}
+		return ByteSize(v * mult), nil
+	}
+
+	if unit == "" || unit == "B" {
+		return 0, fmt.Errorf("fractional amount of bytes in byte size %q", s)
+	}
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("not a valid byte size string: %q", s)
+	}
+	b := math.Round(v * float64(mult))
+	if b >= math.MaxInt64 {
+		return 0, fmt.Errorf("byte size out of range: %q", s)
+	}
+	return ByteSize(b), nil
+}
+
+func (b ByteSize) String() string {
+	if b == 0 {
+		return "0B"
+	}
+	for _, u := range byteUnits {
+		if int64(b)%u.mult == 0 {
+			return strconv.FormatInt(int64(b)/u.mult, 10) + u.name
+		}
+	}
+	return strconv.FormatInt(int64(b), 10) + "B"
+}
+
+// Set implements pflag/flag.Value
+func (b *ByteSize) Set(s string) error {
+	var err error
+	*b, err = ParseByteSize(s)
+	return err
+}
+
+// Type implements pflag.Value
+func (b *ByteSize) Type() string {
+	return "bytesize"
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (b ByteSize) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (b *ByteSize) UnmarshalJSON(bytes []byte) error {
+	var s string
+	if err := json.Unmarshal(bytes, &s); err != nil {
+		return err
+	}
+	return b.Set(s)
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (b ByteSize) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	return b.Set(string(text))
+}
+
+// MarshalYAML implements the yaml.Marshaler interface.
+func (b ByteSize) MarshalYAML() (interface{}, error) {
+	return b.String(), nil
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (b *ByteSize) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return b.Set(s)
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseByteSize(t *testing.T) {
+	cases := []struct {
+		in  string
+		out ByteSize
+		str string
+	}{
+		{in: "0", out: 0, str: "0B"},
+		{in: "1024", out: 1024, str: "1KiB"},
+		{in: "512MB", out: 512e6, str: "512MB"},
+		{in: "1.5GiB", out: 3 << 29, str: "1536MiB"},
+		{in: "2KB", out: 2000, str: "2KB"},
+		{in: "1001B", out: 1001, str: "1001B"},
+		{in: "8EiB", out: -1, str: ""},
+		{in: "7EiB", out: 7 << 60, str: "7EiB"},
+	}
+
+	for _, c := range cases {
+		b, err := ParseByteSize(c.in)
+		if c.out < 0 {
+			if err == nil {
+				t.Errorf("expected out of range error for %q", c.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error on input %q: %s", c.in, err)
+			continue
+		}
+		if b != c.out {
+			t.Errorf("expected %d for %q, got %d", c.out, c.in, b)
+		}
+		if b.String() != c.str {
+			t.Errorf("expected %q for %q, got %q", c.str, c.in, b.String())
+		}
+	}
+}
+
+func TestParseBadByteSize(t *testing.T) {
+	cases := []string{
+		"",
+		"MB",
+		"-1MB",
+		"1.5",
+		"1.5B",
+		"1.2.3MB",
+		"10mb",
+		"10XB",
+	}
+
+	for _, c := range cases {
+		_, err := ParseByteSize(c)
+		if err == nil {
+			t.Errorf("expected error on input %q", c)
+			continue
+		}
+		if !strings.Contains(err.Error(), c) {
+			t.Errorf("expected error for %q to contain the input, got %q", c, err)
+		}
+	}
+}
+
+func TestByteSizeMarshaling(t *testing.T) {
+	var b ByteSize
+	if err := json.Unmarshal([]byte(`"1.5GiB"`), &b); err != nil {
+		t.Fatal(err)
+	}
+	out, err := json.Marshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `"1536MiB"` {
+		t.Errorf("unexpected JSON %s", out)
+	}
+
+	var cfg struct {
+		Limit ByteSize `yaml:"limit"`
+	}
+	if err := yaml.Unmarshal([]byte("limit: 512MB\n"), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Limit != 512e6 {
+		t.Errorf("unexpected YAML value %d", cfg.Limit)
+	}
+	out, err = yaml.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "limit: 512MB\n" {
+		t.Errorf("unexpected YAML %q", out)
+	}
+
+	if err := b.Set("2KiB"); err != nil || b != 2048 {
+		t.Errorf("unexpected Set result %d, %v", b, err)
+	}
+}
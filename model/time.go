@@ -246,11 +246,11 @@ func ParseDuration(s string) (Duration, error) {
 		lastUnitPos = unit.pos
 		// Check if the provided duration overflows time.Duration (> ~ 290years).
 		if v > 1<<63/unit.mult {
-			return 0, errors.New("duration out of range")
+			return 0, fmt.Errorf("duration out of range: %q", orig)
 		}
 		dur += v * unit.mult
 		if dur > 1<<63-1 {
-			return 0, errors.New("duration out of range")
+			return 0, fmt.Errorf("duration out of range: %q", orig)
 		}
 	}
 	return Duration(dur), nil
@@ -0,0 +1,152 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+// +build go1.21
+
+// Package promslog defines standardised ways to initialize slog loggers
+// across Prometheus components.
+// It should typically only ever be imported by main packages.
+package promslog
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+var (
+	LevelFlagOptions  = []string{"debug", "info", "warn", "error"}
+	FormatFlagOptions = []string{"logfmt", "json"}
+)
+
+// AllowedLevel is a settable identifier for the minimum level a log entry
+// must have. Loggers created from a Config with it pick up changes made with
+// Set while they are in use, e.g. from an HTTP endpoint.
+type AllowedLevel struct {
+	lvl *slog.LevelVar
+}
+
+// NewLevel returns an AllowedLevel set to info.
+func NewLevel() *AllowedLevel {
+	l := &AllowedLevel{}
+	_ = l.Set("info")
+	return l
+}
+
+func (l *AllowedLevel) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	type plain string
+	if err := unmarshal((*plain)(&s)); err != nil {
+		return err
+	}
+	if s == "" {
+		return nil
+	}
+	return l.Set(s)
+}
+
+// String returns the name of the allowed level, as passed to Set. It is
+// derived from the level itself, so that it is safe to call concurrently with
+// Set.
+func (l *AllowedLevel) String() string {
+	if l.lvl == nil {
+		return ""
+	}
+	return strings.ToLower(l.lvl.Level().String())
+}
+
+// Level returns the slog level an AllowedLevel stands for.
+func (l *AllowedLevel) Level() slog.Level {
+	if l.lvl == nil {
+		return slog.LevelInfo
+	}
+	return l.lvl.Level()
+}
+
+// Set updates the value of the allowed level.
+func (l *AllowedLevel) Set(s string) error {
+	var lvl slog.Level
+	switch s {
+	case "debug":
+		lvl = slog.LevelDebug
+	case "info":
+		lvl = slog.LevelInfo
+	case "warn":
+		lvl = slog.LevelWarn
+	case "error":
+		lvl = slog.LevelError
+	default:
+		return fmt.Errorf("unrecognized log level %q", s)
+	}
+	if l.lvl == nil {
+		l.lvl = &slog.LevelVar{}
+	}
+	l.lvl.Set(lvl)
+	return nil
+}
+
+// AllowedFormat is a settable identifier for the output format that the logger can have.
+type AllowedFormat struct {
+	s string
+}
+
+func (f *AllowedFormat) String() string {
+	return f.s
+}
+
+// Set updates the value of the allowed format.
+func (f *AllowedFormat) Set(s string) error {
+	switch s {
+	case "logfmt", "json":
+		f.s = s
+	default:
+		return fmt.Errorf("unrecognized log format %q", s)
+	}
+	return nil
+}
+
+// Config is a struct containing configurable settings for the logger.
+type Config struct {
+	Level  *AllowedLevel
+	Format *AllowedFormat
+	// Writer is where log entries are written to, stderr if nil.
+	Writer io.Writer
+}
+
+// New returns a new slog logger. Each logged line will be annotated with a
+// timestamp and the source location. Without a level, entries of level info
+// and above are logged.
+func New(config *Config) *slog.Logger {
+	w := config.Writer
+	if w == nil {
+		w = os.Stderr
+	}
+	if config.Level == nil {
+		config.Level = NewLevel()
+	}
+	if config.Level.lvl == nil {
+		_ = config.Level.Set("info")
+	}
+
+	opts := &slog.HandlerOptions{
+		AddSource: true,
+		Level:     config.Level.lvl,
+	}
+	if config.Format != nil && config.Format.s == "json" {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
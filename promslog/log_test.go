@@ -0,0 +1,134 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.21
+// +build go1.21
+
+package promslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Make sure creating and using a logger with an empty configuration doesn't
+// result in a panic.
+func TestDefaultConfig(t *testing.T) {
+	logger := New(&Config{})
+	logger.Info("hello", "world", true)
+}
+
+func TestUnmarshallLevel(t *testing.T) {
+	l := &AllowedLevel{}
+	err := yaml.Unmarshal([]byte(`debug`), l)
+	if err != nil {
+		t.Error(err)
+	}
+	if l.String() != "debug" {
+		t.Errorf("expected %s, got %s", "debug", l.String())
+	}
+}
+
+func TestUnmarshallEmptyLevel(t *testing.T) {
+	l := &AllowedLevel{}
+	err := yaml.Unmarshal([]byte(``), l)
+	if err != nil {
+		t.Error(err)
+	}
+	if l.String() != "" {
+		t.Errorf("expected empty level, got %s", l.String())
+	}
+}
+
+func TestUnmarshallBadLevel(t *testing.T) {
+	l := &AllowedLevel{}
+	err := yaml.Unmarshal([]byte(`debugg`), l)
+	if err == nil {
+		t.Error("expected error")
+	}
+	expErr := `unrecognized log level "debugg"`
+	if err.Error() != expErr {
+		t.Errorf("expected error %s, got %s", expErr, err.Error())
+	}
+	if l.String() != "" {
+		t.Errorf("expected empty level, got %s", l.String())
+	}
+}
+
+func TestDynamicLevel(t *testing.T) {
+	var buf bytes.Buffer
+	lvl := NewLevel()
+	logger := New(&Config{Level: lvl, Writer: &buf})
+
+	logger.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("expected debug entry to be dropped, got %q", buf.String())
+	}
+
+	if err := lvl.Set("debug"); err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("shown")
+	if !strings.Contains(buf.String(), "msg=shown") {
+		t.Errorf("expected debug entry after level change, got %q", buf.String())
+	}
+}
+
+func TestConcurrentLevel(t *testing.T) {
+	lvl := NewLevel()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			_ = lvl.Set(LevelFlagOptions[i%len(LevelFlagOptions)])
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		_ = lvl.String()
+	}
+	wg.Wait()
+	if s := lvl.String(); s != "error" {
+		t.Errorf("expected error, got %s", s)
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	format := &AllowedFormat{}
+	if err := format.Set("json"); err != nil {
+		t.Fatal(err)
+	}
+	logger := New(&Config{Format: format, Writer: &buf})
+	logger.Warn("hello", "answer", 42)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected JSON output, got %q: %v", buf.String(), err)
+	}
+	if entry["msg"] != "hello" || entry["level"] != "WARN" || entry["answer"] != float64(42) {
+		t.Errorf("unexpected entry %v", entry)
+	}
+}
+
+func TestBadFormat(t *testing.T) {
+	f := &AllowedFormat{}
+	if err := f.Set("xml"); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"sync"
+	"time"
+)
+
+// Budget limits the number of retries per key within a sliding time window,
+// so that a failing dependency isn't hit by ever more retries from many
+// callers. It is safe for concurrent use.
+type Budget struct {
+	max    int
+	window time.Duration
+	now    func() time.Time
+
+	mtx     sync.Mutex
+	retries map[string][]time.Time
+	// The last time keys without retries within the window were removed.
+	swept time.Time
+}
+
+// NewBudget returns a Budget allowing max retries per key within window.
+func NewBudget(max int, window time.Duration) *Budget {
+	return &Budget{
+		max:     max,
+		window:  window,
+		now:     time.Now,
+		retries: map[string][]time.Time{},
+	}
+}
+
+// take reports whether a retry for key is allowed, and records it if so.
+func (b *Budget) take(key string) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	now := b.now()
+	b.sweep(now)
+	retries := b.prune(b.retries[key], now)
+	if len(retries) >= b.max {
+		if len(retries) == 0 {
+			delete(b.retries, key)
+		} else {
+			b.retries[key] = retries
+		}
+		return false
+	}
+	b.retries[key] = append(retries, now)
+	return true
+}
+
+// prune returns the retries which are still within the window.
+func (b *Budget) prune(retries []time.Time, now time.Time) []time.Time {
+	i := 0
+	for i < len(retries) && now.Sub(retries[i]) >= b.window {
+		i++
+	}
+	return retries[i:]
+}
+
+// sweep removes all keys without retries within the window, so that keys
+// which aren't retried anymore don't accumulate. It only does so once per
+// window, as it has to look at all keys.
+func (b *Budget) sweep(now time.Time) {
+	if now.Sub(b.swept) < b.window {
+		return
+	}
+	b.swept = now
+	for key, retries := range b.retries {
+		if len(b.prune(retries, now)) == 0 {
+			delete(b.retries, key)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package retry provides a helper retrying operations with exponential
+// backoff and full jitter, with limits on attempts and elapsed time, and
+// optional retry budgets preventing retry storms.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// ErrBudgetExhausted is returned, wrapping the last error, when the retry
+// budget set with WithBudget doesn't allow another retry.
+var ErrBudgetExhausted = errors.New("retry budget exhausted")
+
+// Option configures Do.
+type Option func(*options)
+
+type options struct {
+	initial     time.Duration
+	max         time.Duration
+	multiplier  float64
+	jitter      bool
+	maxAttempts int
+	maxElapsed  time.Duration
+	retryable   func(error) bool
+	budget      *Budget
+	budgetKey   string
+}
+
+// WithBackoff sets the delay before the first retry, 100ms by default, and
+// the maximum delay it grows to, 10s by default.
+func WithBackoff(initial, max time.Duration) Option {
+	return func(o *options) {
+		o.initial, o.max = initial, max
+	}
+}
+
+// WithMultiplier sets the factor the delay grows by after each retry, 2 by
+// default.
+func WithMultiplier(m float64) Option {
+	return func(o *options) {
+		o.multiplier = m
+	}
+}
+
+// WithoutJitter makes Do wait for the full delay between attempts, instead
+// of a random duration between zero and the delay.
+func WithoutJitter() Option {
+	return func(o *options) {
+		o.jitter = false
+	}
+}
+
+// WithMaxAttempts sets the maximum number of attempts, including the first
+// one, 5 by default. Zero or less means no limit.
+func WithMaxAttempts(n int) Option {
+	return func(o *options) {
+		o.maxAttempts = n
+	}
+}
+
+// WithMaxElapsed stops retrying once the next attempt would start later than
+// d after the first one. There is no limit by default.
+func WithMaxElapsed(d time.Duration) Option {
+	return func(o *options) {
+		o.maxElapsed = d
+	}
+}
+
+// WithIsRetryable sets the function classifying errors. Errors it returns
+// false for are returned right away. By default all errors are retried.
+func WithIsRetryable(fn func(error) bool) Option {
+	return func(o *options) {
+		o.retryable = fn
+	}
+}
+
+// WithBudget makes each retry take from the budget kept for key, e.g. a
+// target host. Once it's exhausted, Do stops retrying.
+func WithBudget(b *Budget, key string) Option {
+	return func(o *options) {
+		o.budget, o.budgetKey = b, key
+	}
+}
+
+// Do calls fn until it succeeds, returns an error that isn't retryable, or
+// one of the limits is reached, waiting with exponential backoff between
+// attempts. It returns the last error of fn, wrapped with the context's error
+// if ctx is done while waiting, or with ErrBudgetExhausted.
+func Do(ctx context.Context, fn func(ctx context.Context) error, opts ...Option) error {
+	o := options{
+		initial:     100 * time.Millisecond,
+		max:         10 * time.Second,
+		multiplier:  2,
+		jitter:      true,
+		maxAttempts: 5,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	start := time.Now()
+	delay := o.initial
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil || (o.retryable != nil && !o.retryable(err)) {
+			return err
+		}
+		if o.maxAttempts > 0 && attempt >= o.maxAttempts {
+			return err
+		}
+
+		wait := delay
+		if o.jitter && wait > 0 {
+			wait = time.Duration(rand.Int63n(int64(wait) + 1))
+		}
+		if o.maxElapsed > 0 && time.Since(start)+wait > o.maxElapsed {
+			return err
+		}
+		if o.budget != nil && !o.budget.take(o.budgetKey) {
+			return fmt.Errorf("%w: %w", ErrBudgetExhausted, err)
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("%w: last error: %w", ctx.Err(), err)
+		case <-t.C:
+		}
+
+		delay = time.Duration(float64(delay) * o.multiplier)
+		if delay > o.max || delay <= 0 {
+			delay = o.max
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errTest = errors.New("test error")
+
+func failing(calls *int, succeedAt int) func(context.Context) error {
+	return func(context.Context) error {
+		*calls++
+		if *calls == succeedAt {
+			return nil
+		}
+		return errTest
+	}
+}
+
+func TestDo(t *testing.T) {
+	var calls int
+	err := Do(context.Background(), failing(&calls, 3), WithBackoff(time.Millisecond, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestDoMaxAttempts(t *testing.T) {
+	var calls int
+	err := Do(context.Background(), failing(&calls, 0), WithBackoff(time.Millisecond, time.Millisecond), WithMaxAttempts(4))
+	if !errors.Is(err, errTest) {
+		t.Fatalf("expected the last error, got %v", err)
+	}
+	if calls != 4 {
+		t.Errorf("expected 4 calls, got %d", calls)
+	}
+}
+
+func TestDoMaxElapsed(t *testing.T) {
+	var calls int
+	err := Do(context.Background(), failing(&calls, 0), WithBackoff(time.Hour, time.Hour), WithoutJitter(), WithMaxElapsed(time.Minute))
+	if !errors.Is(err, errTest) {
+		t.Fatalf("expected the last error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected no retry exceeding the elapsed time, got %d calls", calls)
+	}
+}
+
+func TestDoIsRetryable(t *testing.T) {
+	var calls int
+	err := Do(context.Background(), failing(&calls, 0), WithIsRetryable(func(err error) bool { return false }))
+	if !errors.Is(err, errTest) {
+		t.Fatalf("expected the error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestDoContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var calls int
+	err := Do(ctx, failing(&calls, 0), WithBackoff(time.Hour, time.Hour), WithoutJitter())
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, errTest) {
+		t.Fatalf("expected the context and last error, got %v", err)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	b := NewBudget(2, time.Minute)
+	now := time.Unix(0, 0)
+	b.now = func() time.Time { return now }
+
+	var calls int
+	err := Do(context.Background(), failing(&calls, 0), WithBackoff(0, 0), WithMaxAttempts(0), WithBudget(b, "a"))
+	if !errors.Is(err, ErrBudgetExhausted) || !errors.Is(err, errTest) {
+		t.Fatalf("expected the budget to be exhausted, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 2 retries, got %d calls", calls-1)
+	}
+
+	if !b.take("b") {
+		t.Error("expected a separate budget per key")
+	}
+	if b.take("a") {
+		t.Error("expected the budget to still be exhausted")
+	}
+	now = now.Add(time.Minute)
+	if !b.take("a") {
+		t.Error("expected the budget to recover after the window")
+	}
+
+	// Keys without retries within the window are removed.
+	now = now.Add(time.Minute)
+	b.take("c")
+	if _, ok := b.retries["a"]; ok {
+		t.Error("expected the expired key a to be removed")
+	}
+	if _, ok := b.retries["b"]; ok {
+		t.Error("expected the expired key b to be removed")
+	}
+	if len(b.retries) != 1 {
+		t.Errorf("expected only key c to be kept, got %v", b.retries)
+	}
+}
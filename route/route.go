@@ -42,6 +42,7 @@ type Router struct {
 	rtr    *httprouter.Router
 	prefix string
 	instrh func(handlerName string, handler http.HandlerFunc) http.HandlerFunc
+	mw     []func(http.Handler) http.Handler
 }
 
 // New returns a new Router.
@@ -59,16 +60,28 @@ func (r *Router) WithInstrumentation(instrh func(handlerName string, handler htt
 			return newInstrh(handlerName, r.instrh(handlerName, handler))
 		}
 	}
-	return &Router{rtr: r.rtr, prefix: r.prefix, instrh: instrh}
+	return &Router{rtr: r.rtr, prefix: r.prefix, instrh: instrh, mw: r.mw}
 }
 
 // WithPrefix returns a router that prefixes all registered routes with prefix.
 func (r *Router) WithPrefix(prefix string) *Router {
-	return &Router{rtr: r.rtr, prefix: r.prefix + prefix, instrh: r.instrh}
+	return &Router{rtr: r.rtr, prefix: r.prefix + prefix, instrh: r.instrh, mw: r.mw}
+}
+
+// WithMiddleware returns a router wrapping the handlers of all registered
+// routes in the middleware mw, in addition to any middleware added before. The
+// first middleware is the outermost one. Route parameters are available to
+// the middleware, which is run within instrumentation.
+func (r *Router) WithMiddleware(mw ...func(http.Handler) http.Handler) *Router {
+	chain := append(append([]func(http.Handler) http.Handler{}, r.mw...), mw...)
+	return &Router{rtr: r.rtr, prefix: r.prefix, instrh: r.instrh, mw: chain}
 }
 
 // handle turns a HandlerFunc into an httprouter.Handle.
 func (r *Router) handle(handlerName string, h http.HandlerFunc) httprouter.Handle {
+	for i := len(r.mw) - 1; i >= 0; i-- {
+		h = r.mw[i](h).ServeHTTP
+	}
 	if r.instrh != nil {
 		// This needs to be outside the closure to avoid data race when reading and writing to 'h'.
 		h = r.instrh(handlerName, h)
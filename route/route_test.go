@@ -190,3 +190,34 @@ func TestInstrumentations(t *testing.T) {
 		}
 	}
 }
+
+func TestMiddleware(t *testing.T) {
+	var got []string
+	tag := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = append(got, name+":"+Param(r.Context(), "id"))
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	router := New().WithMiddleware(tag("1")).WithPrefix("/api").WithMiddleware(tag("2"), tag("3"))
+	router.Get("/foo/:id", func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, "handler")
+	})
+
+	r, err := http.NewRequest("GET", "http://localhost:9090/api/foo/42", nil)
+	if err != nil {
+		t.Fatalf("Error building test request: %s", err)
+	}
+	router.ServeHTTP(nil, r)
+	want := []string{"1:42", "2:42", "3:42", "handler"}
+	if len(want) != len(got) {
+		t.Fatalf("Unexpected value: want %q, got %q", want, got)
+	}
+	for i, v := range want {
+		if v != got[i] {
+			t.Fatalf("Unexpected value: want %q, got %q", want, got)
+		}
+	}
+}
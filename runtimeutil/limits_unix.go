@@ -0,0 +1,32 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build unix
+// +build unix
+
+package runtimeutil
+
+import "syscall"
+
+// FdLimits returns the limits on the number of open file descriptors.
+func FdLimits() (Limit, error) {
+	return getLimit(syscall.RLIMIT_NOFILE)
+}
+
+func getLimit(resource int) (Limit, error) {
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(resource, &rlimit); err != nil {
+		return Limit{}, err
+	}
+	return Limit{Soft: uint64(rlimit.Cur), Hard: uint64(rlimit.Max)}, nil
+}
@@ -0,0 +1,45 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package runtimeutil provides information about the running process, such as
+// its uptime and resource limits, and a handler rendering it for status pages.
+package runtimeutil
+
+import (
+	"errors"
+	"time"
+)
+
+// errUnsupported is returned by the limit functions on platforms without
+// resource limits.
+var errUnsupported = errors.New("not supported on this platform")
+
+var startTime = time.Now()
+
+// StartTime returns the time the process started at, more precisely the time
+// this package was initialized.
+func StartTime() time.Time {
+	return startTime
+}
+
+// Uptime returns the time passed since StartTime.
+func Uptime() time.Duration {
+	return time.Since(startTime)
+}
+
+// Limit is a resource limit of the process. The hard limit is the ceiling the
+// soft limit, which is enforced, can be raised to.
+type Limit struct {
+	Soft uint64 `json:"soft"`
+	Hard uint64 `json:"hard"`
+}
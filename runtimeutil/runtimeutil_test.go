@@ -0,0 +1,77 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtimeutil
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUptime(t *testing.T) {
+	if StartTime().After(time.Now()) {
+		t.Fatal("expected the start time to be in the past")
+	}
+	if Uptime() <= 0 {
+		t.Fatal("expected a positive uptime")
+	}
+}
+
+func TestFdLimits(t *testing.T) {
+	l, err := FdLimits()
+	if errors.Is(err, errUnsupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Soft == 0 || l.Soft > l.Hard {
+		t.Fatalf("unexpected limits %+v", l)
+	}
+}
+
+func TestStatusHandler(t *testing.T) {
+	h := StatusHandler()
+
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected JSON, got %q", ct)
+	}
+	var s Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.GoVersion != runtime.Version() || s.GOMAXPROCS != runtime.GOMAXPROCS(0) {
+		t.Errorf("unexpected status %+v", s)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/status", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if ct := rec.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Fatalf("expected HTML, got %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "<td>"+runtime.Version()+"</td>") {
+		t.Errorf("expected the Go version in the page, got %s", rec.Body.String())
+	}
+}
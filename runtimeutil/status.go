@@ -0,0 +1,154 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runtimeutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/prometheus/common/internal/bitbucket.org/ww/goautoneg"
+	"github.com/prometheus/common/version"
+)
+
+// Status is a snapshot of information about the running process.
+type Status struct {
+	StartTime    time.Time   `json:"startTime"`
+	Uptime       string      `json:"uptime"`
+	GoVersion    string      `json:"goVersion"`
+	GOMAXPROCS   int         `json:"GOMAXPROCS"`
+	NumCPU       int         `json:"numCPU"`
+	NumGoroutine int         `json:"numGoroutine"`
+	FdLimits     *Limit      `json:"fdLimits,omitempty"`
+	VMLimits     *Limit      `json:"vmLimits,omitempty"`
+	GC           GCStatus    `json:"gc"`
+	Build        BuildStatus `json:"build"`
+}
+
+// GCStatus holds statistics of the garbage collector and heap.
+type GCStatus struct {
+	NumGC        uint32    `json:"numGC"`
+	LastGC       time.Time `json:"lastGC"`
+	PauseTotalNs uint64    `json:"pauseTotalNs"`
+	HeapAlloc    uint64    `json:"heapAlloc"`
+	HeapSys      uint64    `json:"heapSys"`
+	NextGC       uint64    `json:"nextGC"`
+}
+
+// BuildStatus holds the build information of the version package.
+type BuildStatus struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision"`
+	Branch    string `json:"branch"`
+	BuildUser string `json:"buildUser"`
+	BuildDate string `json:"buildDate"`
+	Tags      string `json:"tags"`
+}
+
+// CurrentStatus returns the status of the running process. Limits which can't
+// be determined on the platform are left out. Reading the GC statistics
+// briefly stops the world.
+func CurrentStatus() Status {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	s := Status{
+		StartTime:    StartTime(),
+		Uptime:       Uptime().Round(time.Second).String(),
+		GoVersion:    runtime.Version(),
+		GOMAXPROCS:   runtime.GOMAXPROCS(0),
+		NumCPU:       runtime.NumCPU(),
+		NumGoroutine: runtime.NumGoroutine(),
+		GC: GCStatus{
+			NumGC:        ms.NumGC,
+			PauseTotalNs: ms.PauseTotalNs,
+			HeapAlloc:    ms.HeapAlloc,
+			HeapSys:      ms.HeapSys,
+			NextGC:       ms.NextGC,
+		},
+		Build: BuildStatus{
+			Version:   version.Version,
+			Revision:  version.GetRevision(),
+			Branch:    version.Branch,
+			BuildUser: version.BuildUser,
+			BuildDate: version.BuildDate,
+			Tags:      version.GetTags(),
+		},
+	}
+	if ms.LastGC > 0 {
+		s.GC.LastGC = time.Unix(0, int64(ms.LastGC))
+	}
+	if l, err := FdLimits(); err == nil {
+		s.FdLimits = &l
+	}
+	if l, err := VMLimits(); err == nil {
+		s.VMLimits = &l
+	}
+	return s
+}
+
+var statusTmpl = template.Must(template.New("status").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Status</title></head>
+<body>
+<h1>Status</h1>
+<table>
+<tr><th>Start time</th><td>{{.StartTime.Format "2006-01-02T15:04:05Z07:00"}}</td></tr>
+<tr><th>Uptime</th><td>{{.Uptime}}</td></tr>
+<tr><th>Version</th><td>{{.Build.Version}}</td></tr>
+<tr><th>Revision</th><td>{{.Build.Revision}}</td></tr>
+<tr><th>Branch</th><td>{{.Build.Branch}}</td></tr>
+<tr><th>Build user</th><td>{{.Build.BuildUser}}</td></tr>
+<tr><th>Build date</th><td>{{.Build.BuildDate}}</td></tr>
+<tr><th>Build tags</th><td>{{.Build.Tags}}</td></tr>
+<tr><th>Go version</th><td>{{.GoVersion}}</td></tr>
+<tr><th>GOMAXPROCS</th><td>{{.GOMAXPROCS}}</td></tr>
+<tr><th>CPUs</th><td>{{.NumCPU}}</td></tr>
+<tr><th>Goroutines</th><td>{{.NumGoroutine}}</td></tr>
+{{with .FdLimits}}<tr><th>File descriptor limits</th><td>soft={{.Soft}}, hard={{.Hard}}</td></tr>
+{{end}}{{with .VMLimits}}<tr><th>Virtual memory limits</th><td>soft={{.Soft}}, hard={{.Hard}}</td></tr>
+{{end}}<tr><th>GC runs</th><td>{{.GC.NumGC}}</td></tr>
+<tr><th>GC pause total</th><td>{{.GC.PauseTotalNs}}ns</td></tr>
+<tr><th>Heap allocated</th><td>{{.GC.HeapAlloc}} bytes</td></tr>
+<tr><th>Heap obtained from the OS</th><td>{{.GC.HeapSys}} bytes</td></tr>
+<tr><th>Next GC target</th><td>{{.GC.NextGC}} bytes</td></tr>
+</table>
+</body>
+</html>
+`))
+
+// StatusHandler returns a handler rendering CurrentStatus as an HTML page, or
+// as JSON if the request prefers application/json.
+func StatusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := CurrentStatus()
+		w.Header().Add("Vary", "Accept")
+		if goautoneg.Negotiate(r.Header.Get("Accept"), []string{"text/html", "application/json"}) == "application/json" {
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(s); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		var buf bytes.Buffer
+		if err := statusTmpl.Execute(&buf, s); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(buf.Bytes())
+	})
+}
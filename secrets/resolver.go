@@ -0,0 +1,130 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Ref configures where a secret is taken from: inline, from a file or from an
+// environment variable. At most one of the fields may be set.
+type Ref struct {
+	Secret     Secret `yaml:"secret,omitempty" json:"secret,omitempty"`
+	SecretFile string `yaml:"secret_file,omitempty" json:"secret_file,omitempty"`
+	SecretEnv  string `yaml:"secret_env,omitempty" json:"secret_env,omitempty"`
+}
+
+// Validate returns an error if more than one source of the secret is set.
+func (r *Ref) Validate() error {
+	n := 0
+	for _, set := range []bool{r.Secret != "", r.SecretFile != "", r.SecretEnv != ""} {
+		if set {
+			n++
+		}
+	}
+	if n > 1 {
+		return errors.New("at most one of secret, secret_file & secret_env must be configured")
+	}
+	return nil
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (r *Ref) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain Ref
+	if err := unmarshal((*plain)(r)); err != nil {
+		return err
+	}
+	return r.Validate()
+}
+
+// SetDirectory joins any relative file path with dir.
+func (r *Ref) SetDirectory(dir string) {
+	if r.SecretFile != "" && !filepath.IsAbs(r.SecretFile) {
+		r.SecretFile = filepath.Join(dir, r.SecretFile)
+	}
+}
+
+// Read returns the secret from its configured source. Surrounding whitespace
+// is trimmed from the content of files. An unset environment variable is an
+// error, while an empty Ref returns an empty secret.
+func (r *Ref) Read() (Secret, error) {
+	switch {
+	case r.SecretFile != "":
+		b, err := os.ReadFile(r.SecretFile)
+		if err != nil {
+			return "", fmt.Errorf("unable to read secret file %s: %w", r.SecretFile, err)
+		}
+		return Secret(strings.TrimSpace(string(b))), nil
+	case r.SecretEnv != "":
+		v, ok := os.LookupEnv(r.SecretEnv)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s for secret is not set", r.SecretEnv)
+		}
+		return Secret(v), nil
+	}
+	return r.Secret, nil
+}
+
+// Resolver returns the secret of a Ref, reading it again once a refresh
+// interval passed, so that rotated credentials are picked up. It is safe for
+// concurrent use.
+type Resolver struct {
+	ref     Ref
+	refresh time.Duration
+	now     func() time.Time
+
+	mtx    sync.Mutex
+	secret Secret
+	read   time.Time
+	ok     bool
+}
+
+// NewResolver returns a Resolver for ref. If refresh is positive, the secret
+// is read again on the first Get after refresh passed since the last read,
+// otherwise it is only read once.
+func NewResolver(ref Ref, refresh time.Duration) (*Resolver, error) {
+	if err := ref.Validate(); err != nil {
+		return nil, err
+	}
+	return &Resolver{ref: ref, refresh: refresh, now: time.Now}, nil
+}
+
+// Get returns the secret. If reading it again fails, the previously read
+// secret is returned along with the error, so that callers can keep using it
+// while a credential file is being replaced, but still notice that it may be
+// stale. Reading is retried on the next Get.
+func (r *Resolver) Get() (Secret, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	now := r.now()
+	if r.ok && (r.refresh <= 0 || now.Sub(r.read) < r.refresh) {
+		return r.secret, nil
+	}
+	secret, err := r.ref.Read()
+	if err != nil {
+		if r.ok {
+			return r.secret, err
+		}
+		return "", err
+	}
+	r.secret, r.read, r.ok = secret, now, true
+	return secret, nil
+}
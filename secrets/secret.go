@@ -0,0 +1,63 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package secrets provides a string type for sensitive configuration values,
+// which is redacted whenever it is marshalled or formatted, and the resolution
+// of such values from inline configuration, files or environment variables.
+package secrets
+
+import (
+	"encoding/json"
+)
+
+const secretToken = "<secret>"
+
+// Secret is a string holding sensitive content. It marshals to "<secret>" in
+// YAML and JSON and formats as "<secret>" with the fmt package, unless empty.
+// The content is obtained by converting it to a string.
+type Secret string
+
+// String implements the fmt.Stringer interface.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return secretToken
+}
+
+// GoString implements the fmt.GoStringer interface.
+func (s Secret) GoString() string {
+	return `"` + s.String() + `"`
+}
+
+// MarshalYAML implements the yaml.Marshaler interface.
+func (s Secret) MarshalYAML() (interface{}, error) {
+	if s != "" {
+		return secretToken, nil
+	}
+	return nil, nil
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (s *Secret) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain Secret
+	return unmarshal((*plain)(s))
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (s Secret) MarshalJSON() ([]byte, error) {
+	if len(s) == 0 {
+		return json.Marshal("")
+	}
+	return json.Marshal(secretToken)
+}
@@ -0,0 +1,160 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestSecretRedaction(t *testing.T) {
+	s := Secret("hunter2")
+	for _, out := range []string{
+		fmt.Sprint(s),
+		fmt.Sprintf("%s %v %q %#v", s, s, s, s),
+		fmt.Sprintf("%v", struct{ S Secret }{s}),
+	} {
+		if out == "" || strings.Contains(out, "hunter2") {
+			t.Errorf("expected the secret to be redacted, got %q", out)
+		}
+	}
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out string
+	if err := json.Unmarshal(b, &out); err != nil || out != "<secret>" {
+		t.Errorf("unexpected JSON %s", b)
+	}
+	y, err := yaml.Marshal(struct {
+		S Secret `yaml:"s"`
+	}{s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(y) != "s: <secret>\n" {
+		t.Errorf("unexpected YAML %q", y)
+	}
+	if string(s) != "hunter2" {
+		t.Errorf("expected the content to be retained, got %q", string(s))
+	}
+}
+
+func TestRefRead(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "token"), []byte("from file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SECRETS_TEST_TOKEN", "from env")
+
+	for in, expected := range map[string]Secret{
+		"secret: inline":                 "inline",
+		"secret_file: token":             "from file",
+		"secret_env: SECRETS_TEST_TOKEN": "from env",
+		"{}":                             "",
+	} {
+		var ref Ref
+		if err := yaml.UnmarshalStrict([]byte(in), &ref); err != nil {
+			t.Fatal(err)
+		}
+		ref.SetDirectory(dir)
+		s, err := ref.Read()
+		if err != nil {
+			t.Fatalf("%s: %v", in, err)
+		}
+		if s != expected {
+			t.Errorf("%s: expected %q, got %q", in, string(expected), string(s))
+		}
+	}
+
+	var ref Ref
+	if err := yaml.Unmarshal([]byte("secret: a\nsecret_env: B"), &ref); err == nil {
+		t.Error("expected an error for several sources")
+	}
+	ref = Ref{SecretEnv: "SECRETS_TEST_UNSET"}
+	if _, err := ref.Read(); err == nil {
+		t.Error("expected an error for an unset environment variable")
+	}
+}
+
+func TestResolverRefresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("first")
+
+	r, err := NewResolver(Ref{SecretFile: path}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(0, 0)
+	r.now = func() time.Time { return now }
+
+	get := func(expected Secret) {
+		t.Helper()
+		s, err := r.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != expected {
+			t.Fatalf("expected %q, got %q", string(expected), string(s))
+		}
+	}
+	get("first")
+	write("second")
+	get("first")
+	now = now.Add(time.Minute)
+	get("second")
+
+	// The last secret is kept while the file is missing, but the error is
+	// reported.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Minute)
+	s, err := r.Get()
+	if err == nil {
+		t.Fatal("expected an error for the missing file")
+	}
+	if s != "second" {
+		t.Fatalf("expected the stale secret, got %q", string(s))
+	}
+	write("third")
+	get("third")
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewResolver(Ref{Secret: "a", SecretFile: path}, 0); err == nil {
+		t.Error("expected an error for an invalid ref")
+	}
+	r, err = NewResolver(Ref{SecretFile: path}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Get(); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// ListenAndServe listens on srv.Addr and serves requests with srv, as
+// configured by the web configuration file at cfgPath, see WebConfig. With an
+// empty cfgPath, plain HTTP is served. On SIGTERM or SIGINT the server is shut
+// down gracefully, giving in-flight requests up to the configured shutdown
+// timeout to finish, after which nil is returned.
+func ListenAndServe(srv *http.Server, cfgPath string) error {
+	cfg := &WebConfig{HTTPConfig: HTTPConfig{HTTP2: true}, ShutdownTimeout: defaultShutdownTimeout}
+	if cfgPath != "" {
+		var err error
+		if cfg, err = LoadWebConfigFile(cfgPath); err != nil {
+			return err
+		}
+	}
+
+	addr := srv.Addr
+	if addr == "" {
+		addr = ":http"
+		if cfg.tlsEnabled() {
+			addr = ":https"
+		}
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return serve(ctx, l, srv, cfg)
+}
+
+// serve serves requests on l until ctx is done, then shuts srv down.
+func serve(ctx context.Context, l net.Listener, srv *http.Server, cfg *WebConfig) error {
+	errs := make(chan error, 1)
+	if cfg.tlsEnabled() {
+		tlsConfig, err := cfg.newTLSConfig()
+		if err != nil {
+			l.Close()
+			return err
+		}
+		if !cfg.HTTPConfig.HTTP2 {
+			// A non-nil map keeps ServeTLS from enabling HTTP/2.
+			srv.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+		}
+		srv.TLSConfig = tlsConfig
+		go func() { errs <- srv.ServeTLS(l, "", "") }()
+	} else {
+		go func() { errs <- srv.Serve(l) }()
+	}
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeout))
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+)
+
+// defaultShutdownTimeout is the time given to in-flight requests to finish
+// when shutting down, unless configured otherwise.
+const defaultShutdownTimeout = model.Duration(30 * time.Second)
+
+// WebConfig configures the HTTP server started by ListenAndServe.
+type WebConfig struct {
+	TLSConfig  TLSConfig  `yaml:"tls_server_config"`
+	HTTPConfig HTTPConfig `yaml:"http_server_config"`
+	// ShutdownTimeout is the time given to in-flight requests to finish on
+	// shutdown. It defaults to 30s.
+	ShutdownTimeout model.Duration `yaml:"shutdown_timeout,omitempty"`
+}
+
+// TLSConfig configures TLS for the HTTP server. TLS is enabled if a
+// certificate is configured.
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ClientCAFile is the CA used to verify client certificates, as needed
+	// for mutual TLS.
+	ClientCAFile string `yaml:"client_ca_file"`
+	// ClientAuth is the policy for client certificates, one of the names of
+	// the tls.ClientAuthType constants, e.g. "RequireAndVerifyClientCert".
+	ClientAuth   string            `yaml:"client_auth_type"`
+	MinVersion   config.TLSVersion `yaml:"min_version"`
+	MaxVersion   config.TLSVersion `yaml:"max_version"`
+	CipherSuites []Cipher          `yaml:"cipher_suites"`
+}
+
+// HTTPConfig configures the HTTP protocol of the server.
+type HTTPConfig struct {
+	// HTTP2 enables HTTP/2 for TLS connections. It defaults to true.
+	HTTP2 bool `yaml:"http2"`
+}
+
+// Cipher is a TLS cipher suite, configured by its name, e.g.
+// "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256".
+type Cipher uint16
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface for Cipher.
+func (c *Cipher) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		if suite.Name == s {
+			*c = Cipher(suite.ID)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown cipher suite %q", s)
+}
+
+// MarshalYAML implements the yaml.Marshaler interface for Cipher.
+func (c Cipher) MarshalYAML() (interface{}, error) {
+	return tls.CipherSuiteName(uint16(c)), nil
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":                           tls.NoClientCert,
+	"NoClientCert":               tls.NoClientCert,
+	"RequestClientCert":          tls.RequestClientCert,
+	"RequireAnyClientCert":       tls.RequireAnyClientCert,
+	"VerifyClientCertIfGiven":    tls.VerifyClientCertIfGiven,
+	"RequireAndVerifyClientCert": tls.RequireAndVerifyClientCert,
+}
+
+// defaultWebConfig returns a WebConfig with all defaults set.
+func defaultWebConfig() WebConfig {
+	return WebConfig{
+		HTTPConfig:      HTTPConfig{HTTP2: true},
+		ShutdownTimeout: defaultShutdownTimeout,
+	}
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface for WebConfig.
+func (c *WebConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = defaultWebConfig()
+	type plain WebConfig
+	return unmarshal((*plain)(c))
+}
+
+// LoadWebConfigFile parses and validates the given YAML file. Relative file
+// names in it are resolved against the directory of the file.
+func LoadWebConfigFile(filename string) (*WebConfig, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	// UnmarshalYAML isn't called for empty files, so set the defaults here too.
+	c := defaultWebConfig()
+	cfg := &c
+	if err := yaml.UnmarshalStrict(content, cfg); err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(filename)
+	cfg.TLSConfig.CertFile = config.JoinDir(dir, cfg.TLSConfig.CertFile)
+	cfg.TLSConfig.KeyFile = config.JoinDir(dir, cfg.TLSConfig.KeyFile)
+	cfg.TLSConfig.ClientCAFile = config.JoinDir(dir, cfg.TLSConfig.ClientCAFile)
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate validates the WebConfig.
+func (c *WebConfig) Validate() error {
+	tc := c.TLSConfig
+	if (tc.CertFile == "") != (tc.KeyFile == "") {
+		return errors.New("both cert_file and key_file must be set for TLS")
+	}
+	if tc.CertFile == "" && (tc.ClientCAFile != "" || tc.ClientAuth != "" || len(tc.CipherSuites) > 0) {
+		return errors.New("TLS settings require cert_file and key_file to be set")
+	}
+	if _, ok := clientAuthTypes[tc.ClientAuth]; !ok {
+		return fmt.Errorf("invalid client_auth_type %q", tc.ClientAuth)
+	}
+	if tc.ClientCAFile != "" && clientAuthTypes[tc.ClientAuth] == tls.NoClientCert {
+		return errors.New("client_ca_file requires client_auth_type to be set")
+	}
+	if tc.MinVersion != 0 && tc.MaxVersion != 0 && tc.MinVersion > tc.MaxVersion {
+		return fmt.Errorf("min_version %s must not be greater than max_version %s", tc.MinVersion.String(), tc.MaxVersion.String())
+	}
+	if c.ShutdownTimeout < 0 {
+		return errors.New("shutdown_timeout must not be negative")
+	}
+	return nil
+}
+
+// tlsEnabled reports whether the WebConfig configures TLS.
+func (c *WebConfig) tlsEnabled() bool {
+	return c.TLSConfig.CertFile != ""
+}
+
+// newTLSConfig returns the TLS configuration of the server. The certificate is
+// read again whenever its files change.
+func (c *WebConfig) newTLSConfig() (*tls.Config, error) {
+	tc := c.TLSConfig
+	reloader := &certReloader{certFile: tc.CertFile, keyFile: tc.KeyFile}
+	if _, err := reloader.GetCertificate(nil); err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		MaxVersion:     uint16(tc.MaxVersion),
+		GetCertificate: reloader.GetCertificate,
+		ClientAuth:     clientAuthTypes[tc.ClientAuth],
+	}
+	if tc.MinVersion != 0 {
+		cfg.MinVersion = uint16(tc.MinVersion)
+	}
+	for _, cs := range tc.CipherSuites {
+		cfg.CipherSuites = append(cfg.CipherSuites, uint16(cs))
+	}
+	if tc.ClientCAFile != "" {
+		pem, err := os.ReadFile(tc.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client_ca_file %s", tc.ClientCAFile)
+		}
+		cfg.ClientCAs = pool
+	}
+	return cfg, nil
+}
+
+// certReloader loads a certificate, reloading it when its files were
+// modified.
+type certReloader struct {
+	certFile, keyFile string
+
+	mtx             sync.Mutex
+	cert            *tls.Certificate
+	certMod, keyMod time.Time
+}
+
+// GetCertificate implements the tls.Config.GetCertificate callback. If
+// reloading a changed certificate fails, the previous one keeps being used.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	certStat, err := os.Stat(r.certFile)
+	if err != nil {
+		return r.fallback(err)
+	}
+	keyStat, err := os.Stat(r.keyFile)
+	if err != nil {
+		return r.fallback(err)
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.cert != nil && certStat.ModTime().Equal(r.certMod) && keyStat.ModTime().Equal(r.keyMod) {
+		return r.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		if r.cert != nil {
+			return r.cert, nil
+		}
+		return nil, err
+	}
+	r.cert, r.certMod, r.keyMod = &cert, certStat.ModTime(), keyStat.ModTime()
+	return r.cert, nil
+}
+
+// fallback returns the previously loaded certificate, or err if there is none.
+func (r *certReloader) fallback(err error) (*tls.Certificate, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.cert != nil {
+		return r.cert, nil
+	}
+	return nil, err
+}
@@ -0,0 +1,230 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+func TestLoadWebConfigFile(t *testing.T) {
+	cfg, err := LoadWebConfigFile("testdata/web.good.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TLSConfig.CertFile != filepath.Join("testdata", "server.crt") {
+		t.Fatalf("expected the cert file to be relative to the config file, got %s", cfg.TLSConfig.CertFile)
+	}
+	if !cfg.HTTPConfig.HTTP2 {
+		t.Fatal("expected HTTP/2 to be enabled by default")
+	}
+	if cfg.ShutdownTimeout != model.Duration(5*time.Second) {
+		t.Fatalf("shutdown timeout is wrong, got %s", cfg.ShutdownTimeout)
+	}
+	if len(cfg.TLSConfig.CipherSuites) != 1 || uint16(cfg.TLSConfig.CipherSuites[0]) != tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 {
+		t.Fatalf("cipher suites are wrong, got %v", cfg.TLSConfig.CipherSuites)
+	}
+}
+
+func TestLoadEmptyWebConfigFile(t *testing.T) {
+	for _, content := range []string{"", "# No settings.\n"} {
+		name := filepath.Join(t.TempDir(), "web.yml")
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := LoadWebConfigFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cfg.HTTPConfig.HTTP2 {
+			t.Fatalf("%q: expected HTTP/2 to be enabled by default", content)
+		}
+		if cfg.ShutdownTimeout != defaultShutdownTimeout {
+			t.Fatalf("%q: shutdown timeout is wrong, got %s", content, cfg.ShutdownTimeout)
+		}
+	}
+}
+
+func TestLoadWebConfigFileErrors(t *testing.T) {
+	for _, c := range []struct {
+		config string
+		err    string
+	}{
+		{config: "tls_server_config:\n  cert_file: server.crt\n", err: "both cert_file and key_file"},
+		{config: "tls_server_config:\n  client_auth_type: RequireAndVerifyClientCert\n", err: "require cert_file"},
+		{config: "tls_server_config:\n  cert_file: a\n  key_file: b\n  client_auth_type: Always\n", err: "invalid client_auth_type"},
+		{config: "tls_server_config:\n  cert_file: a\n  key_file: b\n  client_ca_file: c\n", err: "client_ca_file requires"},
+		{config: "tls_server_config:\n  cert_file: a\n  key_file: b\n  min_version: TLS13\n  max_version: TLS12\n", err: "min_version"},
+		{config: "tls_server_config:\n  cipher_suites: [TLS_NONE]\n", err: "unknown cipher suite"},
+		{config: "unknown: true\n", err: "not found"},
+	} {
+		name := filepath.Join(t.TempDir(), "web.yml")
+		if err := os.WriteFile(name, []byte(c.config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadWebConfigFile(name)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Fatalf("%q: expected an error containing %q, got %v", c.config, c.err, err)
+		}
+	}
+}
+
+func TestServeTLS(t *testing.T) {
+	cfg, err := LoadWebConfigFile("testdata/web.good.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		io.WriteString(w, r.Proto)
+	})}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serve(ctx, l, srv, cfg) }()
+
+	ca, err := os.ReadFile("testdata/tls-ca-chain.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(ca)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: pool},
+		ForceAttemptHTTP2: true,
+	}}
+	url := "https://" + l.Addr().String()
+
+	resp, err := client.Get(url + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "HTTP/2.0" {
+		t.Fatalf("expected HTTP/2 to be used, got %s", body)
+	}
+
+	// In-flight requests finish during shutdown.
+	slow := make(chan error, 1)
+	go func() {
+		resp, err := client.Get(url + "/slow")
+		if err == nil {
+			resp.Body.Close()
+		}
+		slow <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	if err := <-slow; err != nil {
+		t.Fatalf("expected the in-flight request to finish, got %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("expected a clean shutdown, got %v", err)
+	}
+}
+
+func TestServeWithoutHTTP2(t *testing.T) {
+	cfg, err := LoadWebConfigFile("testdata/web.good.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.HTTPConfig.HTTP2 = false
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	})}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go serve(ctx, l, srv, cfg)
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		ForceAttemptHTTP2: true,
+	}}
+	resp, err := client.Get("https://" + l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "HTTP/1.1" {
+		t.Fatalf("expected HTTP/1.1 to be used, got %s", body)
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"server.crt", "server.key"} {
+		content, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := &certReloader{certFile: filepath.Join(dir, "server.crt"), keyFile: filepath.Join(dir, "server.key")}
+	first, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := r.GetCertificate(nil); again != first {
+		t.Fatal("expected the unchanged certificate not to be reloaded")
+	}
+
+	// A broken replacement keeps the previous certificate in use.
+	future := time.Now().Add(time.Hour)
+	if err := os.WriteFile(r.certFile, []byte("broken"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(r.certFile, future, future)
+	if cert, err := r.GetCertificate(nil); err != nil || cert != first {
+		t.Fatalf("expected the previous certificate, got %v", err)
+	}
+
+	// A fixed replacement is loaded.
+	content, _ := os.ReadFile(filepath.Join("testdata", "server.crt"))
+	if err := os.WriteFile(r.certFile, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	future = future.Add(time.Hour)
+	os.Chtimes(r.certFile, future, future)
+	if cert, err := r.GetCertificate(nil); err != nil || cert == first {
+		t.Fatalf("expected the certificate to be reloaded, got %v", err)
+	}
+}
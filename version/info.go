@@ -15,7 +15,9 @@ package version
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"runtime"
 	"strings"
 	"text/template"
@@ -101,3 +103,29 @@ func Info() string {
 func BuildContext() string {
 	return fmt.Sprintf("(go=%s, platform=%s, user=%s, date=%s, tags=%s)", GoVersion, GoOS+"/"+GoArch, BuildUser, BuildDate, GetTags())
 }
+
+// BuildInfoHandler returns a handler responding with the build information as
+// a JSON object.
+func BuildInfoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info := map[string]string{
+			"version":   Version,
+			"revision":  GetRevision(),
+			"branch":    Branch,
+			"buildUser": BuildUser,
+			"buildDate": BuildDate,
+			"goVersion": GoVersion,
+			"goOS":      GoOS,
+			"goArch":    GoArch,
+			"tags":      GetTags(),
+		}
+		// Buffer the output, so that errors can still be reported.
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(info); err != nil {
+			http.Error(w, "error encoding build information: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(buf.Bytes())
+	})
+}
@@ -0,0 +1,59 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package version
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBuildInfoHandler(t *testing.T) {
+	oldVersion, oldBranch := Version, Branch
+	defer func() { Version, Branch = oldVersion, oldBranch }()
+	Version, Branch = "1.2.3", "main"
+
+	rec := httptest.NewRecorder()
+	BuildInfoHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status is wrong, expected %d, got %d", http.StatusOK, rec.Code)
+	}
+	if ctype := rec.Header().Get("Content-Type"); ctype != "application/json" {
+		t.Fatalf("content type is wrong, got %q", ctype)
+	}
+	var info map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+		t.Fatal(err)
+	}
+	for key, expected := range map[string]string{
+		"version":   "1.2.3",
+		"branch":    "main",
+		"revision":  GetRevision(),
+		"buildUser": BuildUser,
+		"buildDate": BuildDate,
+		"goVersion": GoVersion,
+		"goOS":      GoOS,
+		"goArch":    GoArch,
+		"tags":      GetTags(),
+	} {
+		if actual, ok := info[key]; !ok || actual != expected {
+			t.Errorf("%s is wrong, expected %q, got %q", key, expected, actual)
+		}
+	}
+	if len(info) != 9 {
+		t.Errorf("expected 9 fields, got %v", info)
+	}
+}